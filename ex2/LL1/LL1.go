@@ -3,13 +3,21 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
+
+	"ex2/syntax"
+
+	"example.com/m/lexer"
 )
 
+//go:generate go run . -gen rd_parser.go
+
 var CHARCAST = map[string]uint8{
 	`E'`: 'R',
 	`T'`: 'Y',
@@ -19,7 +27,8 @@ const debug = true
 
 type DebugLevel int
 
-const debugLevel = INFO
+var debugLevel = INFO
+
 const (
 	DEBUG DebugLevel = iota
 	INFO
@@ -38,6 +47,12 @@ func unique(uint8Slice []uint8) []uint8 {
 	}
 	return list
 }
+func sortedSymbols(symbols []uint8) []uint8 {
+	sorted := make([]uint8, len(symbols))
+	copy(sorted, symbols)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
 func debugPrintf(level DebugLevel, format string, a ...interface{}) {
 	if level >= debugLevel {
 		fmt.Printf(format, a...)
@@ -58,6 +73,7 @@ type GrammarLL1 struct {
 	first         map[uint8]([]uint8)
 	follow        map[uint8]([]uint8)
 	parseTable    map[uint8](map[uint8]([]uint8))
+	values        []string
 	ready         bool
 }
 
@@ -75,7 +91,7 @@ func (Grammar *GrammarLL1) buildGrammar(grammar_filename string) {
 func (Grammar *GrammarLL1) printFirstFollow() {
 	level := INFO
 	debugPrintf(level, " %10s   %10s\n", "first", "follow")
-	for key, _ := range Grammar.grammar {
+	for _, key := range Grammar.exportNonTerminals() {
 		debugPrintf(level, "%c -> ", key)
 		firstStr := ""
 		followStr := ""
@@ -199,9 +215,7 @@ func (Grammar *GrammarLL1) genUnfoldGrammar() {
 // type Graph struct {
 // 	nodes map[uint8]Node
 // }
-func isNumber(token uint8) bool {
-	return token >= '0' && token <= '9'
-}
+
 func isNonTerminal(token uint8) bool {
 	return token >= 'A' && token <= 'Z'
 }
@@ -357,20 +371,40 @@ func (Grammar *GrammarLL1) printParseTable() {
 	level := INFO
 	debugPrint(level, "\n      ParseTable\n")
 	title := "      "
-	for _, t := range Grammar.terminals {
+	for _, t := range Grammar.exportTerminals() {
 		title += fmt.Sprintf("%-6c", t)
 	}
 	debugPrintf(level, "%s\n", title)
-	for _, nt := range Grammar.nonTerminals {
+	for _, nt := range Grammar.exportNonTerminals() {
 		debugPrintf(level, "%c     ", nt)
 		printStr := ""
-		for _, t := range Grammar.terminals {
+		for _, t := range Grammar.exportTerminals() {
 			printStr += fmt.Sprintf("%-6s", Grammar.parseTable[nt][t])
 		}
 		debugPrintf(level, "%s\n", printStr)
 	}
 }
 
+// lookaheadSet computes from FIRST/FOLLOW the terminals on which the
+// production of key is chosen.
+func (Grammar *GrammarLL1) lookaheadSet(key uint8, token Token) []uint8 {
+	lookahead := make([]uint8, 0)
+	if token[0] == 'e' {
+		lookahead = append(lookahead, Grammar.follow[key]...)
+	} else if isTerminal(token[0]) {
+		lookahead = append(lookahead, token[0])
+	} else if isNonTerminal(token[0]) {
+		for _, first := range Grammar.first[token[0]] {
+			if !isEmptyToken(first) {
+				lookahead = append(lookahead, first)
+			}
+		}
+	} else {
+		debugPrintf(ERROR, "Wrong token: %s\n", token)
+		os.Exit(1)
+	}
+	return lookahead
+}
 func (Grammar *GrammarLL1) genParseTable() {
 	level := INFO
 	debugPrint(level, "\n      genParseTable\n")
@@ -378,28 +412,24 @@ func (Grammar *GrammarLL1) genParseTable() {
 	for key, value := range Grammar.grammar {
 		Grammar.parseTable[key] = make(map[uint8][]uint8)
 		for _, token := range value {
-			if token[0] == 'e' {
-				for _, follow := range Grammar.follow[key] {
-					Grammar.parseTable[key][follow] = token
-				}
-			} else if isTerminal(token[0]) {
-				Grammar.parseTable[key][token[0]] = token
-			} else if isNonTerminal(token[0]) {
-				for _, first := range Grammar.first[token[0]] {
-					Grammar.parseTable[key][first] = token
-				}
-			} else {
-				debugPrintf(ERROR, "Wrong token: %s\n", token)
-				os.Exit(1)
+			for _, t := range Grammar.lookaheadSet(key, token) {
+				Grammar.parseTable[key][t] = token
 			}
 		}
 	}
 	Grammar.printParseTable()
 }
-func printState(stack []uint8, finishStack []uint8, expression string, index int) {
+func stringfyTokens(tokens []lexer.Token) string {
+	str := ""
+	for _, token := range tokens {
+		str += token.Literal
+	}
+	return str
+}
+func printState(stack []uint8, finishStack []uint8, tokens []lexer.Token, index int) {
 	level := INFO
-	leftExppression := expression[:index]
-	rightExppression := expression[index:]
+	leftExppression := stringfyTokens(tokens[:index])
+	rightExppression := stringfyTokens(tokens[index:])
 	debugPrintf(level, "%-10s%5s%-10s\n", "matched", "", "matching")
 	debugPrintf(level, "%10s%5s%-10s\n", leftExppression, "", rightExppression)
 	copystack := make([]uint8, len(stack))
@@ -410,10 +440,10 @@ func printState(stack []uint8, finishStack []uint8, expression string, index int
 	}
 	debugPrintf(level, "%10s%5s%-10s\n\n", finishStack, "", copystack)
 }
-func printErrorState(stack []uint8, finishStack []uint8, expression string, index int) {
+func printErrorState(stack []uint8, finishStack []uint8, tokens []lexer.Token, index int) {
 	level := ERROR
-	leftExppression := expression[:index]
-	rightExppression := expression[index:]
+	leftExppression := stringfyTokens(tokens[:index])
+	rightExppression := stringfyTokens(tokens[index:])
 	debugPrintf(level, "\nError state dump\n")
 	debugPrintf(level, "%-10s%5s%-10s\n", "matched", "", "matching")
 	debugPrintf(level, "%10s%5s%-10s\n", leftExppression, "", rightExppression)
@@ -425,116 +455,239 @@ func printErrorState(stack []uint8, finishStack []uint8, expression string, inde
 	}
 	debugPrintf(level, "%10s%5s%-10s\n", finishStack, "", copystack)
 }
-func (Grammar *GrammarLL1) ParseExpression(expression string) error {
+
+// ParseExpression lexes the expression with the ex1 lexer and parses the
+// resulting token stream.
+func (Grammar *GrammarLL1) ParseExpression(expression string) (*ParseNode, error) {
 	level := INFO
 	debugPrintf(level, "\nParseExpression  %s\n", expression)
+	return Grammar.ParseTokens(lexer.Lex([]byte(expression)))
+}
+
+// ParseTokens runs the table driven LL(1) parser over a token stream and
+// returns the parse tree. The literal of every matched token is kept in
+// Grammar.values. Syntax errors are recovered in panic mode, using the
+// FOLLOW sets as synchronizing tokens, and returned together as syntax.Errors.
+func (Grammar *GrammarLL1) ParseTokens(tokens []lexer.Token) (*ParseNode, error) {
+	level := INFO
 	if !Grammar.ready {
 		debugPrintf(level, "Grammar not builded.\n")
-		return errors.New("Grammar not builded.")
+		return nil, errors.New("Grammar not builded.")
 	}
 	step := 0
 	//add end symbol
-	expression += "#"
-	expression = strings.Replace(expression, " ", "", -1)
+	end := lexer.Token{Type: lexer.EOF, Literal: "#"}
+	if len(tokens) > 0 {
+		end.Line = tokens[len(tokens)-1].Line
+		end.Column = tokens[len(tokens)-1].Column + 1
+	}
+	tokens = append(tokens, end)
 	stack := make([]uint8, 0)
 	finishStack := make([]uint8, 0)
-	stack = append(stack, 'S')
+	syntaxErrors := make(syntax.Errors, 0)
+	//recovering is set while panic mode skips input, no new error is
+	//reported until a token is matched again
+	recovering := false
+	Grammar.values = make([]string, 0)
+	root := newParseNode('S')
+	//nodeStack holds the tree node of every symbol on the stack
+	nodeStack := make([]*ParseNode, 0)
+	stack = append(stack, '#', 'S')
+	nodeStack = append(nodeStack, nil, root)
+	reportError := func(index int, expected []uint8) {
+		if !recovering {
+			printErrorState(stack, finishStack, tokens, index)
+			syntaxErrors = append(syntaxErrors, syntax.Error{Token: tokens[index], State: syntax.NO_STATE, Expected: expected})
+			debugPrintf(ERROR, "Error: %s\n", syntaxErrors[len(syntaxErrors)-1])
+		}
+		recovering = true
+	}
 	for index := 0; len(stack) > 0; {
-		printState(stack, finishStack, expression, index)
-		char := expression[index]
+		printState(stack, finishStack, tokens, index)
+		token := tokens[index]
+		char, ok := syntax.TerminalOf(token)
+		if !ok {
+			//a token the grammar does not know, skip it
+			reportError(index, nil)
+			index++
+			continue
+		}
 		topStack := stack[len(stack)-1]
+		topNode := nodeStack[len(nodeStack)-1]
 		if isTerminal(topStack) {
-			if topStack == char || topStack == 'n' && isNumber(char) {
-				if topStack == char {
-					debugPrintf(level, "step:%d match a operater %c %c\n", step, topStack, char)
-				} else {
-					debugPrintf(level, "step:%d match a number %c %c\n", step, topStack, char)
-				}
+			if topStack == char {
+				debugPrintf(level, "step:%d match %c %s\n", step, topStack, token.Literal)
 				step++
+				recovering = false
 				finishStack = append(finishStack, topStack)
+				Grammar.values = append(Grammar.values, token.Literal)
+				if topNode != nil {
+					topNode.token = token
+				}
 				//pop stack
 				stack = stack[:len(stack)-1]
+				nodeStack = nodeStack[:len(nodeStack)-1]
+				index++
+			} else if topStack == '#' {
+				//input left after the start symbol, skip it
+				reportError(index, []uint8{'#'})
 				index++
 			} else {
-				debugPrintf(ERROR, "Error: %c != %c\n", topStack, char)
-				printErrorState(stack, finishStack, expression, index)
-				return errors.New("Error: " + string(topStack) + " != " + string(char))
+				//pretend the missing terminal was there
+				reportError(index, []uint8{topStack})
+				stack = stack[:len(stack)-1]
+				nodeStack = nodeStack[:len(nodeStack)-1]
 			}
 		} else if isNonTerminal(topStack) {
-			if isNumber(char) {
-				//lookup in ParseTable
-				token := Grammar.parseTable[topStack]['n']
-				//pop stack
-				stack = stack[:len(stack)-1]
-				//push token
-				for i := len(token) - 1; i >= 0; i-- {
-					stack = append(stack, token[i])
-				}
-			} else {
-				//lookup in ParseTable
-				token := Grammar.parseTable[topStack][char]
-				if len(token) == 0 {
-					printErrorState(stack, finishStack, expression, index)
-					return errors.New("Error: NonTerminal [" + string(topStack) + "] lookup fail")
+			//lookup in ParseTable
+			production := Grammar.parseTable[topStack][char]
+			if len(production) == 0 {
+				reportError(index, Grammar.expectedTerminals(topStack))
+				if Grammar.isSynchronizing(topStack, char) {
+					//synchronize, give up on this non-terminal
+					stack = stack[:len(stack)-1]
+					nodeStack = nodeStack[:len(nodeStack)-1]
+				} else {
+					index++
 				}
-				//pop stack
-				stack = stack[:len(stack)-1]
-				//push token
-				for i := len(token) - 1; i >= 0; i-- {
-					stack = append(stack, token[i])
+				continue
+			}
+			//pop stack
+			stack = stack[:len(stack)-1]
+			nodeStack = nodeStack[:len(nodeStack)-1]
+			//expand the node, an empty production gets no children
+			children := make([]*ParseNode, len(production))
+			for i, symbol := range production {
+				if !isEmptyToken(symbol) {
+					children[i] = newParseNode(symbol)
+					topNode.children = append(topNode.children, children[i])
 				}
 			}
+			//push production
+			for i := len(production) - 1; i >= 0; i-- {
+				stack = append(stack, production[i])
+				nodeStack = append(nodeStack, children[i])
+			}
 		} else if topStack == 'e' {
 			stack = stack[:len(stack)-1]
+			nodeStack = nodeStack[:len(nodeStack)-1]
 		} else {
 			debugPrintf(ERROR, "Error: %c\n", topStack)
-			printErrorState(stack, finishStack, expression, index)
-			return errors.New("Error: " + string(topStack) + " != " + string(char))
+			printErrorState(stack, finishStack, tokens, index)
+			return nil, errors.New("Error: " + string(topStack) + " != " + token.Literal)
+		}
+	}
+	if len(syntaxErrors) > 0 {
+		return root, syntaxErrors
+	}
+	return root, nil
+}
+
+// parseAndPrint parses an expression and prints the resulting parse tree.
+func (Grammar *GrammarLL1) parseAndPrint(expression string) {
+	tree, err := Grammar.ParseExpression(expression)
+	if err != nil {
+		debugPrintf(ERROR, "Parse fail\n%s\n", err)
+		return
+	}
+	debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	debugPrintf(INFO, "%s", tree)
+	debugPrintf(INFO, "%s\n", tree.SExpression())
+	debugPrintf(DEBUG, "%s", tree.Dot())
+}
+
+var sampleExpressions = []string{
+	"3+1*(5+6)/7",
+	"3+1/7+",
+	"3+(*6",
+	"(1+)*2)",
+	"12+count*(rate-10)",
+}
+
+// checkRecursiveDescent parses every expression with the table driven parser
+// and the generated recursive descent parser and reports any difference in
+// the parse trees or the syntax errors.
+func (Grammar *GrammarLL1) checkRecursiveDescent(expressions []string) bool {
+	same := true
+	for _, expression := range expressions {
+		tableTree, tableErr := Grammar.ParseExpression(expression)
+		parser := RecursiveDescentParser{}
+		rdTree, rdErr := parser.ParseExpression(expression)
+		tableResult := fmt.Sprint(tableTree.SExpression(), tableErr)
+		rdResult := fmt.Sprint(rdTree.SExpression(), rdErr)
+		if tableResult != rdResult {
+			debugPrintf(ERROR, "Check %s fail\ntable: %s\nrecursive descent: %s\n", expression, tableResult, rdResult)
+			same = false
+		} else {
+			debugPrintf(ERROR, "Check %s ok\n", expression)
 		}
 	}
-	return nil
+	return same
 }
 
 func main() {
-	grammar_filename := "../grammar.txt"
+	grammar_filename := flag.String("grammar", "../grammar.txt", "grammar file")
+	gen := flag.String("gen", "", "write a recursive descent parser for the grammar to this file")
+	pkg := flag.String("pkg", "main", "package of the generated parser")
+	check := flag.Bool("check", false, "compare the table driven and the generated parser")
+	export := flag.String("export", "", "export the grammar, FIRST/FOLLOW and parse table as json, csv, md or html")
+	output := flag.String("o", "", "output file of -export, default stdout")
+	flag.Parse()
+	if *export != "" && *output == "" {
+		//keep stdout clean for the exported table
+		debugLevel = ERROR
+	}
 	Grammar := GrammarLL1{}
 	//read grammar
-	Grammar.buildGrammar(grammar_filename)
-	expression := "3+1*(5+6)/7"
-	err := Grammar.ParseExpression(expression)
-	if err != nil {
-		debugPrintf(ERROR, "Parse fail %s\n", err)
-	} else {
-		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	Grammar.buildGrammar(*grammar_filename)
+	if *gen != "" {
+		err := Grammar.writeRecursiveDescent(*grammar_filename, *pkg, *gen)
+		if err != nil {
+			debugPrintf(ERROR, "Generate parser fail %s\n", err)
+			os.Exit(1)
+		}
+		debugPrintf(ERROR, "Generate parser %s success.\n", *gen)
+		return
 	}
-	expression = "3+1/7+"
-	err = Grammar.ParseExpression(expression)
-	if err != nil {
-		debugPrintf(ERROR, "Parse fail %s\n", err)
-	} else {
-		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	if *export != "" {
+		out := os.Stdout
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				debugPrintf(ERROR, "Export fail %s\n", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+			out = file
+		}
+		err := Grammar.Export(out, *export)
+		if err != nil {
+			debugPrintf(ERROR, "Export fail %s\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	expression = "3+(*6"
-	err = Grammar.ParseExpression(expression)
-	if err != nil {
-		debugPrintf(ERROR, "Parse fail %s\n", err)
-	} else {
-		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	if *check {
+		if !Grammar.checkRecursiveDescent(sampleExpressions) {
+			os.Exit(1)
+		}
+		return
+	}
+	for _, expression := range sampleExpressions {
+		Grammar.parseAndPrint(expression)
 	}
 	//read from stdin
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print("Enter expression: ")
-		expression, _ := reader.ReadString('\n')
+		expression, readErr := reader.ReadString('\n')
+		if readErr != nil && expression == "" {
+			break
+		}
 		expression = strings.Replace(expression, " ", "", -1)
 		expression = strings.Replace(expression, "\r", "", -1)
 		expression = strings.Replace(expression, "\n", "", -1)
-		err = Grammar.ParseExpression(expression)
-		if err != nil {
-			debugPrintf(ERROR, "Parse fail %s\n", err)
-		} else {
-			debugPrintf(ERROR, "Parse expression %s success.\n", expression)
-		}
+		Grammar.parseAndPrint(expression)
 	}
 
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// exportTerminals returns the terminals sorted, with the end marker last.
+func (Grammar *GrammarLL1) exportTerminals() []uint8 {
+	terminals := make([]uint8, 0, len(Grammar.terminals))
+	for _, t := range sortedSymbols(Grammar.terminals) {
+		if t != '#' {
+			terminals = append(terminals, t)
+		}
+	}
+	return append(terminals, '#')
+}
+
+// exportNonTerminals returns the non-terminals sorted, with the start symbol
+// first.
+func (Grammar *GrammarLL1) exportNonTerminals() []uint8 {
+	nonTerminals := []uint8{'S'}
+	for _, nt := range sortedSymbols(Grammar.nonTerminals) {
+		if nt != 'S' {
+			nonTerminals = append(nonTerminals, nt)
+		}
+	}
+	return nonTerminals
+}
+
+func (Grammar *GrammarLL1) isNullable(nonTerminal uint8) bool {
+	for _, first := range Grammar.first[nonTerminal] {
+		if isEmptyToken(first) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportSymbols writes a set of symbols sorted, with the empty string as ε
+// and the end marker last.
+func exportSymbols(symbols []uint8) []string {
+	result := make([]string, 0, len(symbols))
+	end, empty := false, false
+	for _, symbol := range sortedSymbols(unique(symbols)) {
+		if symbol == '#' {
+			end = true
+		} else if isEmptyToken(symbol) {
+			empty = true
+		} else {
+			result = append(result, string(symbol))
+		}
+	}
+	if empty {
+		result = append(result, "ε")
+	}
+	if end {
+		result = append(result, "#")
+	}
+	return result
+}
+
+func exportProduction(token Token) string {
+	if len(token) > 0 && isEmptyToken(token[0]) {
+		return "ε"
+	}
+	return string(token)
+}
+
+type exportRule struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+type grammarExport struct {
+	Start        string                       `json:"start"`
+	Terminals    []string                     `json:"terminals"`
+	NonTerminals []string                     `json:"nonTerminals"`
+	Productions  []exportRule                 `json:"productions"`
+	Nullable     []string                     `json:"nullable"`
+	First        map[string][]string          `json:"first"`
+	Follow       map[string][]string          `json:"follow"`
+	ParseTable   map[string]map[string]string `json:"parseTable"`
+}
+
+// buildExport collects the grammar, FIRST/FOLLOW sets, nullable set and parse
+// table in a stable order.
+func (Grammar *GrammarLL1) buildExport() grammarExport {
+	result := grammarExport{
+		Start:        "S",
+		Terminals:    exportSymbols(Grammar.exportTerminals()),
+		NonTerminals: make([]string, 0),
+		Productions:  make([]exportRule, 0),
+		Nullable:     make([]string, 0),
+		First:        make(map[string][]string),
+		Follow:       make(map[string][]string),
+		ParseTable:   make(map[string]map[string]string),
+	}
+	for _, nt := range Grammar.exportNonTerminals() {
+		key := string(nt)
+		result.NonTerminals = append(result.NonTerminals, key)
+		for _, token := range Grammar.grammar[nt] {
+			result.Productions = append(result.Productions, exportRule{Left: key, Right: exportProduction(token)})
+		}
+		if Grammar.isNullable(nt) {
+			result.Nullable = append(result.Nullable, key)
+		}
+		result.First[key] = exportSymbols(Grammar.first[nt])
+		result.Follow[key] = exportSymbols(Grammar.follow[nt])
+		result.ParseTable[key] = make(map[string]string)
+		for _, t := range Grammar.exportTerminals() {
+			if token := Grammar.parseTable[nt][t]; len(token) > 0 {
+				result.ParseTable[key][string(t)] = exportProduction(token)
+			}
+		}
+	}
+	return result
+}
+
+func (Grammar *GrammarLL1) exportJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(Grammar.buildExport(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// exportCSV writes one row per non-terminal: nullable, FIRST, FOLLOW and the
+// parse table row.
+func (Grammar *GrammarLL1) exportCSV(w io.Writer) error {
+	result := Grammar.buildExport()
+	writer := csv.NewWriter(w)
+	header := []string{"nonterminal", "nullable", "first", "follow"}
+	header = append(header, result.Terminals...)
+	writer.Write(header)
+	for _, key := range result.NonTerminals {
+		row := []string{
+			key,
+			fmt.Sprint(containString(result.Nullable, key)),
+			strings.Join(result.First[key], " "),
+			strings.Join(result.Follow[key], " "),
+		}
+		for _, t := range result.Terminals {
+			row = append(row, result.ParseTable[key][t])
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func containString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func markdownCell(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+func (Grammar *GrammarLL1) exportMarkdown(w io.Writer) error {
+	result := Grammar.buildExport()
+	var buffer bytes.Buffer
+	buffer.WriteString("## Grammar\n\n")
+	for _, rule := range result.Productions {
+		fmt.Fprintf(&buffer, "- `%s -> %s`\n", rule.Left, rule.Right)
+	}
+	buffer.WriteString("\n## FIRST and FOLLOW\n\n")
+	buffer.WriteString("| Non-terminal | Nullable | FIRST | FOLLOW |\n")
+	buffer.WriteString("| --- | --- | --- | --- |\n")
+	for _, key := range result.NonTerminals {
+		fmt.Fprintf(&buffer, "| %s | %t | %s | %s |\n", key, containString(result.Nullable, key),
+			markdownCell(strings.Join(result.First[key], " ")), markdownCell(strings.Join(result.Follow[key], " ")))
+	}
+	buffer.WriteString("\n## Parse table\n\n|  |")
+	for _, t := range result.Terminals {
+		fmt.Fprintf(&buffer, " %s |", markdownCell(t))
+	}
+	buffer.WriteString("\n| --- |")
+	buffer.WriteString(strings.Repeat(" --- |", len(result.Terminals)))
+	buffer.WriteString("\n")
+	for _, key := range result.NonTerminals {
+		fmt.Fprintf(&buffer, "| %s |", key)
+		for _, t := range result.Terminals {
+			if token, ok := result.ParseTable[key][t]; ok {
+				fmt.Fprintf(&buffer, " %s -> %s |", key, markdownCell(token))
+			} else {
+				buffer.WriteString("  |")
+			}
+		}
+		buffer.WriteString("\n")
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+func (Grammar *GrammarLL1) exportHTML(w io.Writer) error {
+	result := Grammar.buildExport()
+	var buffer bytes.Buffer
+	buffer.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>LL(1) grammar</title>\n")
+	buffer.WriteString("<style>table{border-collapse:collapse}td,th{border:1px solid #999;padding:2px 8px}</style>\n")
+	buffer.WriteString("</head>\n<body>\n<h2>Grammar</h2>\n<ul>\n")
+	for _, rule := range result.Productions {
+		fmt.Fprintf(&buffer, "<li><code>%s -&gt; %s</code></li>\n", html.EscapeString(rule.Left), html.EscapeString(rule.Right))
+	}
+	buffer.WriteString("</ul>\n<h2>FIRST and FOLLOW</h2>\n<table>\n")
+	buffer.WriteString("<tr><th>Non-terminal</th><th>Nullable</th><th>FIRST</th><th>FOLLOW</th></tr>\n")
+	for _, key := range result.NonTerminals {
+		fmt.Fprintf(&buffer, "<tr><td>%s</td><td>%t</td><td>%s</td><td>%s</td></tr>\n", key, containString(result.Nullable, key),
+			html.EscapeString(strings.Join(result.First[key], " ")), html.EscapeString(strings.Join(result.Follow[key], " ")))
+	}
+	buffer.WriteString("</table>\n<h2>Parse table</h2>\n<table>\n<tr><th></th>")
+	for _, t := range result.Terminals {
+		fmt.Fprintf(&buffer, "<th>%s</th>", html.EscapeString(t))
+	}
+	buffer.WriteString("</tr>\n")
+	for _, key := range result.NonTerminals {
+		fmt.Fprintf(&buffer, "<tr><th>%s</th>", key)
+		for _, t := range result.Terminals {
+			if token, ok := result.ParseTable[key][t]; ok {
+				fmt.Fprintf(&buffer, "<td>%s -&gt; %s</td>", key, html.EscapeString(token))
+			} else {
+				buffer.WriteString("<td></td>")
+			}
+		}
+		buffer.WriteString("</tr>\n")
+	}
+	buffer.WriteString("</table>\n</body>\n</html>\n")
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// Export writes the grammar, FIRST/FOLLOW sets, nullable set and parse table
+// in one of the formats json, csv, md or html.
+func (Grammar *GrammarLL1) Export(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		return Grammar.exportJSON(w)
+	case "csv":
+		return Grammar.exportCSV(w)
+	case "md", "markdown":
+		return Grammar.exportMarkdown(w)
+	case "html":
+		return Grammar.exportHTML(w)
+	}
+	return fmt.Errorf("unknown export format %s", format)
+}
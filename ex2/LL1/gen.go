@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"strings"
+
+	"ex2/syntax"
+
+	"example.com/m/lexer"
+)
+
+// genParserHeader is written at the top of every generated parser. The
+// generated code reuses ParseNode and newParseNode of the package it is
+// generated into, so it is a drop in replacement of the
+// table driven GrammarLL1.ParseExpression and GrammarLL1.ParseTokens.
+const genParserHeader = `
+import (
+	"ex2/syntax"
+
+	"example.com/m/lexer"
+)
+
+// RecursiveDescentParser is a recursive descent parser with one function per
+// non-terminal, generated from the LL(1) parse table.
+type RecursiveDescentParser struct {
+	tokens     []lexer.Token
+	index      int
+	values     []string
+	errors     syntax.Errors
+	recovering bool
+}
+
+// ParseExpression lexes the expression with the ex1 lexer and parses the
+// resulting token stream.
+func (Parser *RecursiveDescentParser) ParseExpression(expression string) (*ParseNode, error) {
+	return Parser.ParseTokens(lexer.Lex([]byte(expression)))
+}
+
+// ParseTokens parses a token stream and returns the parse tree. Syntax errors
+// are recovered in panic mode and returned together as syntax.Errors.
+func (Parser *RecursiveDescentParser) ParseTokens(tokens []lexer.Token) (*ParseNode, error) {
+	end := lexer.Token{Type: lexer.EOF, Literal: "#"}
+	if len(tokens) > 0 {
+		end.Line = tokens[len(tokens)-1].Line
+		end.Column = tokens[len(tokens)-1].Column + 1
+	}
+	Parser.tokens = append(tokens, end)
+	Parser.index = 0
+	Parser.values = make([]string, 0)
+	Parser.errors = make(syntax.Errors, 0)
+	Parser.recovering = false
+	root := newParseNode('%c')
+	Parser.parse%c(root)
+	//input left after the start symbol is skipped
+	for Parser.lookahead() != '#' {
+		Parser.reportError([]uint8{'#'})
+		Parser.index++
+	}
+	if len(Parser.errors) > 0 {
+		return root, Parser.errors
+	}
+	return root, nil
+}
+
+func (Parser *RecursiveDescentParser) reportError(expected []uint8) {
+	if !Parser.recovering {
+		Parser.errors = append(Parser.errors, syntax.Error{Token: Parser.tokens[Parser.index], State: syntax.NO_STATE, Expected: expected})
+	}
+	Parser.recovering = true
+}
+
+// lookahead returns the terminal of the current token, tokens unknown to the
+// grammar are reported and skipped.
+func (Parser *RecursiveDescentParser) lookahead() uint8 {
+	for {
+		if terminal, ok := genTerminalOf(Parser.tokens[Parser.index]); ok {
+			return terminal
+		}
+		Parser.reportError(nil)
+		Parser.index++
+	}
+}
+
+// expand creates the children of a node for one production, an empty
+// production gets no children.
+func (Parser *RecursiveDescentParser) expand(node *ParseNode, production string) []*ParseNode {
+	children := make([]*ParseNode, len(production))
+	for i := 0; i < len(production); i++ {
+		if production[i] != 'e' {
+			children[i] = newParseNode(production[i])
+			node.children = append(node.children, children[i])
+		}
+	}
+	return children
+}
+
+// match consumes the terminal of a leaf, a missing terminal is reported and
+// treated as present.
+func (Parser *RecursiveDescentParser) match(node *ParseNode, terminal uint8) {
+	if Parser.lookahead() != terminal {
+		Parser.reportError([]uint8{terminal})
+		return
+	}
+	token := Parser.tokens[Parser.index]
+	Parser.recovering = false
+	Parser.values = append(Parser.values, token.Literal)
+	node.token = token
+	Parser.index++
+}
+
+// synchronize reports a lookahead no production accepts. It returns true
+// when the lookahead is in the synchronizing set and the non-terminal is
+// abandoned, otherwise the token is skipped.
+func (Parser *RecursiveDescentParser) synchronize(expected []uint8, synchronizing []uint8) bool {
+	Parser.reportError(expected)
+	terminal := Parser.lookahead()
+	for _, t := range synchronizing {
+		if t == terminal {
+			return true
+		}
+	}
+	Parser.index++
+	return false
+}
+`
+
+func quoteSymbols(symbols []uint8) string {
+	quoted := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		quoted = append(quoted, fmt.Sprintf("%q", symbol))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// genTerminalSwitch writes the mapping of lexer token types to terminals, so
+// the generated parser does not need TERMINALCAST.
+func genTerminalSwitch(builder *strings.Builder) {
+	terminals := make([]uint8, 0, len(syntax.TERMINALCAST))
+	for terminal := range syntax.TERMINALCAST {
+		terminals = append(terminals, terminal)
+	}
+	builder.WriteString("\nfunc genTerminalOf(token lexer.Token) (uint8, bool) {\n")
+	builder.WriteString("\tswitch token.Type {\n")
+	for _, terminal := range sortedSymbols(terminals) {
+		fmt.Fprintf(builder, "\tcase lexer.%s:\n", lexer.TokenTypeStrings[syntax.TERMINALCAST[terminal]])
+		fmt.Fprintf(builder, "\t\treturn %q, true\n", terminal)
+	}
+	builder.WriteString("\t}\n\treturn 0, false\n}\n")
+}
+
+// genNonTerminal writes the function of one non-terminal. Each production is
+// a case on its lookahead set, any other lookahead goes to panic mode.
+func (Grammar *GrammarLL1) genNonTerminal(builder *strings.Builder, key uint8) {
+	fmt.Fprintf(builder, "\nfunc (Parser *RecursiveDescentParser) parse%c(node *ParseNode) {\n", key)
+	builder.WriteString("\tfor {\n\t\tswitch Parser.lookahead() {\n")
+	for _, token := range Grammar.grammar[key] {
+		lookahead := sortedSymbols(unique(Grammar.lookaheadSet(key, token)))
+		if len(lookahead) == 0 {
+			continue
+		}
+		fmt.Fprintf(builder, "\t\tcase %s:\n", quoteSymbols(lookahead))
+		fmt.Fprintf(builder, "\t\t\t// %c -> %s\n", key, token)
+		if isEmptyToken(token[0]) {
+			builder.WriteString("\t\t\treturn\n")
+			continue
+		}
+		fmt.Fprintf(builder, "\t\t\tchildren := Parser.expand(node, %q)\n", string(token))
+		for i, symbol := range token {
+			if isTerminal(symbol) {
+				fmt.Fprintf(builder, "\t\t\tParser.match(children[%d], %q)\n", i, symbol)
+			} else if isNonTerminal(symbol) {
+				fmt.Fprintf(builder, "\t\t\tParser.parse%c(children[%d])\n", symbol, i)
+			}
+		}
+		builder.WriteString("\t\t\treturn\n")
+	}
+	builder.WriteString("\t\t}\n")
+	synchronizing := unique(append([]uint8{'#'}, Grammar.follow[key]...))
+	fmt.Fprintf(builder, "\t\tif Parser.synchronize([]uint8{%s}, []uint8{%s}) {\n",
+		quoteSymbols(Grammar.expectedTerminals(key)), quoteSymbols(sortedSymbols(synchronizing)))
+	builder.WriteString("\t\t\treturn\n\t\t}\n\t}\n}\n")
+}
+
+// genRecursiveDescent generates the Go source of a recursive descent parser
+// for the grammar, in package pkg.
+func (Grammar *GrammarLL1) genRecursiveDescent(grammar_filename string, pkg string) ([]byte, error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "// Code generated by LL1 -gen from %s. DO NOT EDIT.\n\n", grammar_filename)
+	fmt.Fprintf(&builder, "package %s\n", pkg)
+	fmt.Fprintf(&builder, genParserHeader, 'S', 'S')
+	genTerminalSwitch(&builder)
+	for _, key := range sortedSymbols(Grammar.nonTerminals) {
+		Grammar.genNonTerminal(&builder, key)
+	}
+	return format.Source([]byte(builder.String()))
+}
+
+// writeRecursiveDescent generates the recursive descent parser and writes it
+// to filename.
+func (Grammar *GrammarLL1) writeRecursiveDescent(grammar_filename string, pkg string, filename string) error {
+	source, err := Grammar.genRecursiveDescent(grammar_filename, pkg)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, source, 0666)
+}
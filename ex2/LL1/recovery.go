@@ -0,0 +1,28 @@
+package main
+
+// expectedTerminals lists, sorted, the terminals that have an entry in the
+// parse table row of a non-terminal.
+func (Grammar *GrammarLL1) expectedTerminals(nonTerminal uint8) []uint8 {
+	expected := make([]uint8, 0)
+	for _, t := range sortedSymbols(Grammar.terminals) {
+		if len(Grammar.parseTable[nonTerminal][t]) > 0 {
+			expected = append(expected, t)
+		}
+	}
+	return expected
+}
+
+// isSynchronizing reports whether the panic mode recovery may pop the
+// non-terminal on this lookahead, the synchronizing set of a non-terminal
+// is its FOLLOW set plus the end marker.
+func (Grammar *GrammarLL1) isSynchronizing(nonTerminal uint8, terminal uint8) bool {
+	if terminal == '#' {
+		return true
+	}
+	for _, follow := range Grammar.follow[nonTerminal] {
+		if follow == terminal {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/m/lexer"
+)
+
+// ParseNode is a node of the concrete parse tree built by the LL(1) driver.
+// A non-terminal node has one child per symbol of the production it was
+// expanded with, a terminal node is a leaf holding the matched token.
+type ParseNode struct {
+	symbol   uint8
+	token    lexer.Token
+	children []*ParseNode
+}
+
+func newParseNode(symbol uint8) *ParseNode {
+	return &ParseNode{
+		symbol:   symbol,
+		children: make([]*ParseNode, 0),
+	}
+}
+
+func (node *ParseNode) isLeaf() bool {
+	return isTerminal(node.symbol)
+}
+
+// label is the text shown for a node: the non-terminal itself, or the
+// terminal followed by the literal of the matched token when they differ.
+func (node *ParseNode) label() string {
+	if node.isLeaf() && node.token.Literal != string(node.symbol) {
+		return fmt.Sprintf("%c %s", node.symbol, node.token.Literal)
+	}
+	return string(node.symbol)
+}
+
+// String prints the tree as indented text, one node per line.
+func (node *ParseNode) String() string {
+	var builder strings.Builder
+	node.writeIndented(&builder, 0)
+	return builder.String()
+}
+func (node *ParseNode) writeIndented(builder *strings.Builder, depth int) {
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString(node.label())
+	builder.WriteString("\n")
+	for _, child := range node.children {
+		child.writeIndented(builder, depth+1)
+	}
+}
+
+// SExpression prints the tree as an S-expression, leaves are written as the
+// literal of the matched token.
+func (node *ParseNode) SExpression() string {
+	if node.isLeaf() {
+		return node.token.Literal
+	}
+	str := "(" + string(node.symbol)
+	for _, child := range node.children {
+		str += " " + child.SExpression()
+	}
+	return str + ")"
+}
+
+// Dot prints the tree as a Graphviz digraph.
+func (node *ParseNode) Dot() string {
+	var builder strings.Builder
+	builder.WriteString("digraph ParseTree {\n")
+	id := 0
+	node.writeDot(&builder, &id)
+	builder.WriteString("}\n")
+	return builder.String()
+}
+func (node *ParseNode) writeDot(builder *strings.Builder, id *int) int {
+	self := *id
+	*id++
+	if node.isLeaf() {
+		fmt.Fprintf(builder, "\tnode%d [label=%s, shape=box];\n", self, strconv.Quote(node.label()))
+	} else {
+		fmt.Fprintf(builder, "\tnode%d [label=%s];\n", self, strconv.Quote(node.label()))
+	}
+	for _, child := range node.children {
+		childId := child.writeDot(builder, id)
+		fmt.Fprintf(builder, "\tnode%d -> node%d;\n", self, childId)
+	}
+	return self
+}
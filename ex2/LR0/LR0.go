@@ -2,11 +2,13 @@ package main
 
 import (
 	"bufio"
-	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -19,7 +21,8 @@ const debug = true
 
 type DebugLevel int
 
-const debugLevel = INFO
+var debugLevel = INFO
+
 const (
 	DEBUG DebugLevel = iota
 	INFO
@@ -51,10 +54,33 @@ func debugPrint(level DebugLevel, format string) {
 }
 
 type Token []uint8
+
+// Production is one alternative of the grammar, its index in
+// GrammarLR0.unfoldGrammar is the production id. Production 0 is the
+// augmented start production. action names the semantic action run when
+// the production is reduced, written as E -> E+T { add } in the grammar file.
+// prec is the terminal named by %prec in a yacc grammar, the production takes
+// its precedence instead of that of its rightmost terminal, 0 when none.
+type Production struct {
+	left   uint8
+	right  Token
+	action string
+	prec   uint8
+}
+
+// Precedence is declared for terminals with %left, %right or %nonassoc,
+// later declarations bind tighter.
+type Precedence struct {
+	level         int
+	associativity string
+}
+
+// RunToken is an item, the production id with a dot at index. LR(1) items
+// carry the sorted set of lookahead terminals, LR(0) items leave it nil.
 type RunToken struct {
-	token Token
-	left  uint8
-	index int
+	production int
+	index      int
+	lookahead  []uint8
 }
 type Node struct {
 	id         int
@@ -63,45 +89,97 @@ type Node struct {
 	reduceAble bool
 }
 type GrammarLR0 struct {
-	grammar       map[uint8]([]Token)
-	terminals     []uint8
-	nonTerminals  []uint8
-	unfoldGrammar [](map[uint8](Token))
-	closure       []Node
-	ready         bool
+	grammar          map[uint8]([]Token)
+	semantic         map[uint8]([]string)
+	prec             map[uint8]([]uint8)
+	actions          map[string]SemanticAction
+	terminals        []uint8
+	nonTerminals     []uint8
+	unfoldGrammar    []Production
+	productionsOf    map[uint8][]int
+	symbolNames      map[uint8]string
+	precedence       map[uint8]Precedence
+	precedenceLevels int
+	start            uint8
+	augmentedStart   uint8
+	method           string
+	closure          []Node
+	stateIndex       map[string]int
+	lookup           func(Grammar *GrammarLR0, key string, kernel []RunToken) (int, []RunToken, bool)
+	first            map[uint8]([]uint8)
+	nullable         map[uint8]bool
+	follow           map[uint8]([]uint8)
+	table            *LRTable
+	ready            bool
 }
 
-func (Grammar *GrammarLR0) buildGrammar(grammar_filename string) {
+// buildGrammar reads the grammar and builds the automaton and table of one
+// method: LR0, SLR, LALR or LR1.
+func (Grammar *GrammarLR0) buildGrammar(grammar_filename string, method string) {
+	Grammar.method = strings.ToUpper(method)
 	Grammar.grammar = make(map[uint8]([]Token))
-	Grammar.readGrammarFromFile(grammar_filename)
+	Grammar.semantic = make(map[uint8]([]string))
+	Grammar.prec = make(map[uint8]([]uint8))
+	Grammar.actions = make(map[string]SemanticAction)
+	for name, action := range SEMANTIC_ACTIONS {
+		Grammar.actions[name] = action
+	}
+	Grammar.symbolNames = make(map[uint8]string)
+	Grammar.precedence = make(map[uint8]Precedence)
+	if strings.HasSuffix(grammar_filename, ".y") {
+		Grammar.readYaccGrammar(grammar_filename)
+	} else {
+		Grammar.readGrammarFromFile(grammar_filename)
+	}
 	Grammar.genTerminalAndNonterminal()
+	Grammar.augmentGrammar()
 	Grammar.genUnfoldGrammar()
+	Grammar.genFirst()
+	Grammar.genFollow()
+	Grammar.printFirstFollow()
 	Grammar.genClosure()
 	Grammar.printGrammarJumpTable()
+	switch Grammar.method {
+	case "LR0":
+		Grammar.table = Grammar.genLR0Table()
+	case "SLR":
+		Grammar.table = Grammar.genSLRTable()
+	case "LALR", "LR1":
+		Grammar.table = Grammar.genLR1Table(Grammar.method)
+	default:
+		log.Printf("Error: unknown method %s", method)
+		os.Exit(1)
+	}
+	Grammar.printTable(Grammar.table)
 	Grammar.ready = true
 }
 
-func printGrammar(grammar map[uint8]([]Token)) {
+func (Grammar *GrammarLR0) printGrammar() {
 	level := INFO
-	for key, value := range grammar {
+	for _, key := range Grammar.nonTerminals {
 		debugPrintf(level, "%c -> ", key)
-		for _, token := range value {
+		for _, token := range Grammar.grammar[key] {
 			debugPrintf(level, "%s | ", token)
 		}
 		debugPrint(level, "\n")
 	}
 	debugPrint(level, "\n")
 }
-func printUnfoldGrammar(unfoldGrammar [](map[uint8](Token))) {
+func printUnfoldGrammar(unfoldGrammar []Production) {
 	level := DEBUG
-	for _, value := range unfoldGrammar {
-		for key, token := range value {
-			debugPrintf(level, "%c -> %s", key, token)
-			debugPrint(level, "\n")
+	for id, production := range unfoldGrammar {
+		debugPrintf(level, "%d: %c -> %s", id, production.left, production.right)
+		if production.action != "" {
+			debugPrintf(level, " {%s}", production.action)
 		}
+		debugPrint(level, "\n")
 	}
 	debugPrint(level, "\n")
 }
+
+// readGrammarFromFile reads the productions in file order. The left side of
+// the first production is the start symbol, e stands for an empty production
+// and a trailing {name} names the semantic action of the alternative.
 func (Grammar *GrammarLR0) readGrammarFromFile(grammar_filename string) {
 	//declear a empty map from uint8 to slice uint8
 	//read file
@@ -110,7 +188,7 @@ func (Grammar *GrammarLR0) readGrammarFromFile(grammar_filename string) {
 		log.Print(err)
 	}
 	//[]byte to string
-	s := string(b)
+	s := castErrorTokens(string(b))
 	//remove space
 	s = strings.Replace(s, " ", "", -1)
 	s = strings.Replace(s, "\r", "", -1)
@@ -120,6 +198,13 @@ func (Grammar *GrammarLR0) readGrammarFromFile(grammar_filename string) {
 	//split by line
 	lines := strings.Split(string(s), "\n")
 	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "%") {
+			Grammar.readPrecedence(line)
+			continue
+		}
 		//split by ->
 		tokens := strings.Split(line, "->")
 		//check tokens length
@@ -136,14 +221,74 @@ func (Grammar *GrammarLR0) readGrammarFromFile(grammar_filename string) {
 		//check if key in grammar
 		key := tokens[0][0]
 		if _, ok := Grammar.grammar[key]; !ok {
+			if len(Grammar.nonTerminals) == 0 {
+				Grammar.start = key
+			}
 			Grammar.grammar[key] = make([]Token, 0)
+			Grammar.semantic[key] = make([]string, 0)
+			Grammar.nonTerminals = append(Grammar.nonTerminals, key)
 		}
 		for _, token := range split_tokens {
+			action := ""
+			if begin := strings.Index(token, "{"); begin >= 0 && strings.HasSuffix(token, "}") {
+				action = token[begin+1 : len(token)-1]
+				token = token[:begin]
+			}
+			if token == "e" {
+				token = ""
+			}
 			Grammar.grammar[key] = append(Grammar.grammar[key], Token(token))
+			Grammar.semantic[key] = append(Grammar.semantic[key], action)
 		}
 
 	}
-	printGrammar(Grammar.grammar)
+	Grammar.printGrammar()
+}
+
+// castErrorTokens replaces error by ERROR_SYMBOL where it is a whole token
+// of a production, not part of a longer name or of a {name} action.
+func castErrorTokens(s string) string {
+	isNameChar := func(ch byte) bool {
+		return ch == '_' || ch == '\'' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+	}
+	out := make([]byte, 0, len(s))
+	inAction := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '{':
+			inAction = true
+		case s[i] == '}':
+			inAction = false
+		case !inAction && strings.HasPrefix(s[i:], "error") &&
+			(i == 0 || !isNameChar(s[i-1])) && (i+5 == len(s) || !isNameChar(s[i+5])):
+			out = append(out, ERROR_SYMBOL)
+			i += 4
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// readPrecedence reads a %left, %right or %nonassoc line, every character
+// after the keyword is a terminal of the new precedence level.
+func (Grammar *GrammarLR0) readPrecedence(line string) {
+	level := INFO
+	for _, associativity := range []string{"left", "right", "nonassoc"} {
+		if strings.HasPrefix(line, "%"+associativity) {
+			Grammar.precedenceLevels++
+			for _, terminal := range []byte(line[len(associativity)+1:]) {
+				Grammar.precedence[terminal] = Precedence{
+					level:         Grammar.precedenceLevels,
+					associativity: associativity,
+				}
+				debugPrintf(level, "precedence %c %s %d\n", terminal, associativity, Grammar.precedenceLevels)
+			}
+			return
+		}
+	}
+	log.Print("Error: grammar file format error")
+	os.Exit(1)
 }
 func printSlice(level DebugLevel, slice []uint8) {
 	for _, v := range slice {
@@ -153,40 +298,71 @@ func printSlice(level DebugLevel, slice []uint8) {
 }
 func (Grammar *GrammarLR0) genTerminalAndNonterminal() {
 	level := INFO
-	for key, value := range Grammar.grammar {
-		Grammar.nonTerminals = append(Grammar.nonTerminals, key)
-		for _, token := range value {
+	for _, key := range Grammar.nonTerminals {
+		for _, token := range Grammar.grammar[key] {
 			for _, char := range token {
 				if isTerminal(char) {
 					Grammar.terminals = append(Grammar.terminals, char)
+				} else if _, ok := Grammar.grammar[char]; !ok {
+					log.Printf("Error: non terminal %c has no production", char)
+					os.Exit(1)
 				}
 			}
 		}
 	}
 	Grammar.terminals = append(Grammar.terminals, '#')
 	Grammar.terminals = unique(Grammar.terminals)
-	Grammar.nonTerminals = unique(Grammar.nonTerminals)
 	debugPrint(level, "print terminal\n")
 	printSlice(level, Grammar.terminals)
 	debugPrint(level, "print nonTerminal\n")
 	printSlice(level, Grammar.nonTerminals)
 	debugPrint(level, "\n")
 }
+
+// augmentGrammar adds the production S' -> S, S' is the first upper case
+// letter, counting down from Z, the grammar does not use yet.
+func (Grammar *GrammarLR0) augmentGrammar() {
+	level := INFO
+	for symbol := uint8('Z'); symbol >= 'A'; symbol-- {
+		if _, ok := Grammar.grammar[symbol]; !ok {
+			Grammar.augmentedStart = symbol
+			Grammar.grammar[symbol] = []Token{Token{Grammar.start}}
+			Grammar.semantic[symbol] = []string{""}
+			Grammar.nonTerminals = append([]uint8{symbol}, Grammar.nonTerminals...)
+			debugPrintf(level, "augmented start %c -> %c\n\n", symbol, Grammar.start)
+			return
+		}
+	}
+	log.Print("Error: no free non terminal left for the augmented start symbol")
+	os.Exit(1)
+}
+
+// genUnfoldGrammar numbers the productions and indexes them by their left
+// side.
 func (Grammar *GrammarLR0) genUnfoldGrammar() {
-	Grammar.unfoldGrammar = make([](map[uint8](Token)), 0)
-	for key, value := range Grammar.grammar {
-		for _, token := range value {
-			Grammar.unfoldGrammar = append(Grammar.unfoldGrammar, map[uint8](Token){key: token})
+	Grammar.unfoldGrammar = make([]Production, 0)
+	Grammar.productionsOf = make(map[uint8][]int)
+	for _, key := range Grammar.nonTerminals {
+		for i, token := range Grammar.grammar[key] {
+			//only a yacc grammar has %prec
+			var prec uint8
+			if i < len(Grammar.prec[key]) {
+				prec = Grammar.prec[key][i]
+			}
+			Grammar.productionsOf[key] = append(Grammar.productionsOf[key], len(Grammar.unfoldGrammar))
+			Grammar.unfoldGrammar = append(Grammar.unfoldGrammar, Production{
+				left:   key,
+				right:  token,
+				action: Grammar.semantic[key][i],
+				prec:   prec,
+			})
 		}
 	}
 	printUnfoldGrammar(Grammar.unfoldGrammar)
 
 }
-func isNumber(token uint8) bool {
-	return token >= '0' && token <= '9'
-}
 func isNonTerminal(token uint8) bool {
-	return token >= 'A' && token <= 'Z'
+	return (token >= 'A' && token <= 'Z') || token >= NONTERMINAL_BASE
 }
 func isEmptyToken(token uint8) bool {
 	return token == 'e'
@@ -195,156 +371,162 @@ func isTerminal(token uint8) bool {
 	return !(isNonTerminal(token) || isEmptyToken(token))
 }
 
-func (Grammar *GrammarLR0) __addRunToken(runToken RunToken) []RunToken {
-	level := DEBUG
-	result := make([]RunToken, 0)
-	if runToken.index < len(runToken.token) {
-		next := runToken.token[runToken.index]
-		if isNonTerminal(next) && next != runToken.left {
-			debugPrintf(level, "[%c] is non terminal add\n", next)
-			for _, token := range Grammar.grammar[next] {
-				r := RunToken{
-					token: Token(token),
-					index: 0,
-					left:  next,
-				}
-				result = append(result, r)
-				debugPrintf(level, "\radd %s\n", token)
-				result = append(result, Grammar.__addRunToken(r)...)
-			}
-		}
+// newRunToken returns the item of a production with the dot at the start.
+func (Grammar *GrammarLR0) newRunToken(production int) RunToken {
+	return RunToken{production: production, index: 0}
+}
+
+// itemLeft returns the left side of the production of an item.
+func (Grammar *GrammarLR0) itemLeft(runtoken RunToken) uint8 {
+	return Grammar.unfoldGrammar[runtoken.production].left
+}
+
+// itemRight returns the right side of the production of an item.
+func (Grammar *GrammarLR0) itemRight(runtoken RunToken) Token {
+	return Grammar.unfoldGrammar[runtoken.production].right
+}
+
+// nextSymbol returns the symbol after the dot, false for a completed item.
+func (Grammar *GrammarLR0) nextSymbol(runtoken RunToken) (uint8, bool) {
+	right := Grammar.unfoldGrammar[runtoken.production].right
+	if runtoken.index >= len(right) {
+		return 0, false
 	}
-	// result = uniqueToken(result)
-	return result
+	return right[runtoken.index], true
 }
-func printRunToken(runtoken RunToken) {
+func (Grammar *GrammarLR0) printRunToken(runtoken RunToken) {
 	level := INFO
-	debugPrintf(level, "%c ", runtoken.left)
+	right := Grammar.itemRight(runtoken)
+	debugPrintf(level, "%c ", Grammar.itemLeft(runtoken))
 	debugPrintf(level, "-> ")
-	debugPrintf(level, "%s.", runtoken.token[:runtoken.index])
-	debugPrintf(level, "%s\n", runtoken.token[runtoken.index:])
+	debugPrintf(level, "%s.", right[:runtoken.index])
+	debugPrintf(level, "%s", right[runtoken.index:])
+	if runtoken.lookahead != nil {
+		debugPrintf(level, " , %s", strings.Join(strings.Split(string(runtoken.lookahead), ""), "/"))
+	}
+	debugPrint(level, "\n")
 }
-func printStateSet(stateSet []RunToken) {
+func (Grammar *GrammarLR0) printStateSet(stateSet []RunToken) {
 	// level := DEBUG
 	for _, runtoken := range stateSet {
-		printRunToken(runtoken)
+		Grammar.printRunToken(runtoken)
 	}
 }
-func containToken(token RunToken, stateSet []RunToken) bool {
-	for _, runtoken := range stateSet {
-		if runtoken.index == token.index &&
-			runtoken.left == token.left &&
-			string(runtoken.token) == string(token.token) {
-			return true
+
+// itemSetKey encodes a set of items, sorted by production and dot, as a map
+// key. The lookaheads are part of the key when withLookahead is set.
+func itemSetKey(stateSet []RunToken, withLookahead bool) string {
+	items := make([]RunToken, len(stateSet))
+	copy(items, stateSet)
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].production != items[j].production {
+			return items[i].production < items[j].production
 		}
-	}
-	return false
-}
-func uniqueToken(runtokens []RunToken) []RunToken {
-	result := make([]RunToken, 0)
-	for _, token := range runtokens {
-		if !containToken(token, result) {
-			result = append(result, token)
+		return items[i].index < items[j].index
+	})
+	var key strings.Builder
+	for _, runtoken := range items {
+		key.WriteString(strconv.Itoa(runtoken.production))
+		key.WriteByte('.')
+		key.WriteString(strconv.Itoa(runtoken.index))
+		if withLookahead {
+			key.WriteByte(',')
+			key.Write(runtoken.lookahead)
 		}
+		key.WriteByte(';')
 	}
-	return result
+	return key.String()
 }
+
+// __expandClosure computes the closure of a kernel with a worklist: every
+// item with the dot before a non terminal adds the items of all its
+// productions, each non terminal is expanded once.
 func (Grammar *GrammarLR0) __expandClosure(stateSet *[]RunToken) {
 	level := DEBUG
-	debugPrint(level, "expandClosure\n")
-	//printStateSet(*stateSet)
-	size := len(*stateSet)
-	for i := 0; i < size; i++ {
-		*stateSet = append(*stateSet, Grammar.__addRunToken((*stateSet)[i])...)
+	if Grammar.withLookahead() {
+		Grammar.__expandLR1Closure(stateSet)
+		return
 	}
-	//remove duplicate
-	*stateSet = uniqueToken(*stateSet)
-	debugPrint(level, "after expandClosure\n")
-	//printStateSet(*stateSet)
-}
-func (Grammar *GrammarLR0) __checkStateSet(stateSet []RunToken) (int, bool) {
-	level := DEBUG
-	//check if stateSet is in closure status
-	for index, node := range Grammar.closure {
-		flag := true
-		if len(stateSet) != len(node.stateSet) {
-			flag = false
+	debugPrint(level, "expandClosure\n")
+	expanded := make(map[uint8]bool)
+	for i := 0; i < len(*stateSet); i++ {
+		next, ok := Grammar.nextSymbol((*stateSet)[i])
+		if !ok || !isNonTerminal(next) || expanded[next] {
 			continue
 		}
-		for _, runtoken := range stateSet {
-			if !containToken(runtoken, node.stateSet) {
-				flag = false
-				break
-			}
-		}
-		if flag {
-			//current state exist
-			debugPrintf(level, "stateSet exist state %d\n", index)
-			return index, true
+		expanded[next] = true
+		debugPrintf(level, "[%c] is non terminal add\n", next)
+		for _, id := range Grammar.productionsOf[next] {
+			*stateSet = append(*stateSet, Grammar.newRunToken(id))
 		}
 	}
-	return -1, false
+	debugPrint(level, "after expandClosure\n")
 }
 
-func (Grammar *GrammarLR0) __makeJump(closureNode *Node, token uint8) {
+// findState finds the state of a goto kernel by its key in
+// Grammar.stateIndex. It returns the index of the state, or the closure of
+// the kernel when it is new, so that only new kernels are closed.
+func (Grammar *GrammarLR0) findState(key string, kernel []RunToken) (int, []RunToken, bool) {
+	if index, ok := Grammar.stateIndex[key]; ok {
+		return index, nil, true
+	}
+	Grammar.__expandClosure(&kernel)
+	return -1, kernel, false
+}
+
+// __makeJump builds goto(state, token): the kernel of items with the dot
+// moved over token is either found among the existing states or closed and
+// added as a new one. The states are found by Grammar.lookup when it is
+// set, by findState otherwise.
+func (Grammar *GrammarLR0) __makeJump(state int, token uint8) {
 	level := DEBUG
-	debugPrintf(level, "make jump from %d token %c\n", closureNode.id, token)
+	debugPrintf(level, "make jump from %d token %c\n", state, token)
 	newStateSet := make([]RunToken, 0)
-	for _, runtoken := range closureNode.stateSet {
-		if runtoken.index < len(runtoken.token) && runtoken.token[runtoken.index] == token {
-			debugPrintf(level, "match token %c at Token %s\n", token, runtoken.token)
-			newStateSet = append(newStateSet, RunToken{
-				token: make(Token, len(runtoken.token)),
-				index: runtoken.index + 1,
-				left:  runtoken.left,
-			})
-			copy(newStateSet[len(newStateSet)-1].token, runtoken.token)
-			newStateSet[len(newStateSet)-1].index = runtoken.index + 1
-			newStateSet[len(newStateSet)-1].left = runtoken.left
-			//printStateSet(newStateSet)
+	for _, runtoken := range Grammar.closure[state].stateSet {
+		if next, ok := Grammar.nextSymbol(runtoken); ok && next == token {
+			debugPrintf(level, "match token %c at Token %s\n", token, Grammar.itemRight(runtoken))
+			next := runtoken
+			next.index++
+			next.lookahead = append([]uint8(nil), runtoken.lookahead...)
+			newStateSet = append(newStateSet, next)
 		}
 	}
-	Grammar.__expandClosure(&newStateSet)
-	index, flag := Grammar.__checkStateSet(newStateSet)
+	lookup := Grammar.lookup
+	if lookup == nil {
+		lookup = (*GrammarLR0).findState
+	}
+	key := itemSetKey(newStateSet, Grammar.withLookahead())
+	index, newStateSet, flag := lookup(Grammar, key, newStateSet)
 	if flag {
-		debugPrintf(level, "exist token %c from %d jump to %d\n", token, closureNode.id, index)
-		closureNode.jumpTable[token] = index
+		debugPrintf(level, "exist token %c from %d jump to %d\n", token, state, index)
+		Grammar.closure[state].jumpTable[token] = index
 	} else {
 		//add a new state
 		debugPrintf(level, "add new state id:%d\n", len(Grammar.closure))
-		closureNode.jumpTable[token] = len(Grammar.closure)
+		Grammar.stateIndex[key] = len(Grammar.closure)
+		Grammar.closure[state].jumpTable[token] = len(Grammar.closure)
 		Grammar.closure = append(Grammar.closure, Node{
 			id:        len(Grammar.closure),
 			stateSet:  newStateSet,
 			jumpTable: make(map[uint8]int),
 		})
-		debugPrintf(level, "not exist token %c from %d jump to %d\n", token, closureNode.id, closureNode.jumpTable[token])
+		debugPrintf(level, "not exist token %c from %d jump to %d\n", token, state, Grammar.closure[state].jumpTable[token])
 
 	}
 }
-func (Grammar *GrammarLR0) __buildJumptable(closureNode *Node) {
+func (Grammar *GrammarLR0) __buildJumptable(state int) {
 	level := DEBUG
-	// closureNode := Grammar.closure[closureIndex]
-	debugPrintf(level, "build jump table %d\n", closureNode.id)
+	debugPrintf(level, "build jump table %d\n", state)
 	buildOk := make(map[uint8]bool)
-	for _, key := range Grammar.nonTerminals {
-		buildOk[key] = false
-	}
-	for _, key := range Grammar.terminals {
-		buildOk[key] = false
-	}
-	for _, runtoken := range closureNode.stateSet {
-		debugPrint(level, "runToken ")
-		//printRunToken(runtoken)
-		if runtoken.index == len(runtoken.token) {
+	for _, runtoken := range Grammar.closure[state].stateSet {
+		next, ok := Grammar.nextSymbol(runtoken)
+		if !ok {
 			//reach the end
-			debugPrintf(level, "reach end state %d can be reduceAble\n", closureNode.id)
-			closureNode.reduceAble = true
-		} else if !buildOk[runtoken.token[runtoken.index]] {
-			Grammar.__makeJump(closureNode, runtoken.token[runtoken.index])
-			//printRunToken(runtoken)
-
-			buildOk[runtoken.token[runtoken.index]] = true
+			debugPrintf(level, "reach end state %d can be reduceAble\n", state)
+			Grammar.closure[state].reduceAble = true
+		} else if !buildOk[next] {
+			Grammar.__makeJump(state, next)
+			buildOk[next] = true
 		}
 	}
 
@@ -358,6 +540,10 @@ func printJumpTable(jumpTable map[uint8]int) {
 }
 func (Grammar *GrammarLR0) printGrammarJumpTable() {
 	level := INFO
+	if level < debugLevel {
+		//building the text costs more than the automaton on big grammars
+		return
+	}
 	title := "state id Reducable?"
 	for _, key := range Grammar.terminals {
 		title += fmt.Sprintf("%5c", key)
@@ -393,176 +579,93 @@ func (Grammar *GrammarLR0) printGrammarJumpTable() {
 	debugPrint(level, "\n")
 }
 
+// withLookahead reports whether the method builds LR(1) items.
+func (Grammar *GrammarLR0) withLookahead() bool {
+	return Grammar.method == "LALR" || Grammar.method == "LR1"
+}
+
+// genClosure builds the canonical LR(0) collection, or the canonical LR(1)
+// one for LR1 and LALR, starting from the closure of the augmented item
+// S' -> .S with lookahead #. LALR then merges the states with equal cores.
 func (Grammar *GrammarLR0) genClosure() {
 	level := INFO
+	start := Grammar.newRunToken(0)
+	if Grammar.withLookahead() {
+		start.lookahead = []uint8{'#'}
+	}
 	Grammar.closure = make([]Node, 0)
+	Grammar.stateIndex = make(map[string]int)
+	Grammar.stateIndex[itemSetKey([]RunToken{start}, Grammar.withLookahead())] = 0
 	Grammar.closure = append(Grammar.closure, Node{
 		id:        0,
 		jumpTable: make(map[uint8]int),
-		stateSet:  make([]RunToken, 0),
-	})
-	Grammar.closure[0].stateSet = append(Grammar.closure[0].stateSet, RunToken{
-		token: Grammar.grammar['Y'][0],
-		index: 0,
-		left:  'Y',
+		stateSet:  []RunToken{start},
 	})
+	Grammar.__expandClosure(&Grammar.closure[0].stateSet)
 	for i := 0; i < len(Grammar.closure); i++ {
-		Grammar.__expandClosure(&Grammar.closure[i].stateSet)
-		Grammar.__buildJumptable(&Grammar.closure[i])
+		Grammar.__buildJumptable(i)
+	}
+	if Grammar.method == "LALR" {
+		Grammar.mergeCores()
 	}
 	for i := 0; i < len(Grammar.closure); i++ {
 		debugPrintf(level, "state id:%d\n", i)
-		printStateSet(Grammar.closure[i].stateSet)
+		Grammar.printStateSet(Grammar.closure[i].stateSet)
 		// debugPrintf(level, "jumptable state %d\n", i)
 		// printJumpTable(Grammar.closure[i].jumpTable)
 	}
 }
-func printLR0State(stack []uint8, state_Stack []int, expression string, index int) {
-	level := INFO
-	leftExppressionstr := "   "
-	rightExppressionstr := "   "
-	leftExppression := expression[:index]
-	rightExppression := expression[index:]
-	for _, value := range leftExppression {
-		leftExppressionstr += fmt.Sprintf("%3c", value)
-	}
-	for _, value := range rightExppression {
-		rightExppressionstr += fmt.Sprintf("%3c", value)
-	}
-	stackstr := ""
-	state_stackstr := ""
-	for _, value := range stack {
-		stackstr += fmt.Sprintf("%3c", value)
-	}
-	for _, value := range state_Stack {
-		state_stackstr += fmt.Sprintf("%3d", value)
+func main() {
+	grammar_filename := flag.String("grammar", "../grammarlr0.txt", "grammar file")
+	method := flag.String("method", "SLR", "table construction method: LR0, SLR, LALR or LR1")
+	compare := flag.Bool("compare", false, "print the states and conflicts of every method and exit")
+	dot := flag.String("dot", "", "write the automaton as Graphviz DOT to this file (- for stdout) and exit")
+	glr := flag.Bool("glr", false, "parse with the GLR driver and list every parse")
+	report := flag.String("report", "", "write a bison style report of the states to this file (- for stdout) and exit")
+	flag.Parse()
+	if *compare {
+		compareMethods(*grammar_filename)
+		return
+	}
+	if *dot == "-" || *report == "-" {
+		//keep stdout clean for the export
+		debugLevel = ERROR
 	}
-	// debugPrintf(level, "%s\n", title)
-	debugPrintf(level, "%-30s%5s%-20s\n", "matched", "", "matching")
-	debugPrintf(level, "%-30s%5s%-20s\n", leftExppressionstr, "", rightExppression)
-	debugPrintf(level, "%-30s%5s%-20s\n", stackstr, "", "")
-	debugPrintf(level, "%-30s%5s%-20s\n", state_stackstr, "", "")
-
-}
-func (Grammar *GrammarLR0) ParseExpression(expression string) error {
-	level := INFO
-	debugPrintf(level, "\nParseExpression  %s\n", expression)
-	if !Grammar.ready {
-		debugPrintf(level, "Grammar not builded.\n")
-		return errors.New("grammar not builded")
-	}
-	step := 0
-	index := 0
-	//add end symbol
-	expression += "#"
-	expression = strings.Replace(expression, " ", "", -1)
-	stack := make([]uint8, 0)
-	state_stack := make([]int, 0)
-	// finishStack := make([]uint8, 0)
-	stack = append([]uint8{'#'}, stack...)
-	state_stack = append(state_stack, 0)
-	for {
-		printLR0State(stack, state_stack, expression, index)
-		if stack[len(stack)-1] == 'Y' {
-			return nil
-		}
-		stateTop := state_stack[len(state_stack)-1]
-		if len(state_stack) < len(stack) {
-			next := stack[len(stack)-1]
-			if value, ok := Grammar.closure[stateTop].jumpTable[next]; ok {
-				//state change
-				state_stack = append(state_stack, value)
+	Grammar := GrammarLR0{}
+	//read grammar
+	Grammar.buildGrammar(*grammar_filename, *method)
+	if *dot != "" || *report != "" {
+		if *dot != "" {
+			if err := writeFile(*dot, Grammar.exportDot); err != nil {
+				log.Fatal(err)
 			}
-		} else {
-			next := expression[index]
-			if isNumber(next) {
-				if value, ok := Grammar.closure[stateTop].jumpTable['n']; ok {
-					//state change
-					state_stack = append(state_stack, value)
-					stack = append(stack, 'n')
-					index++
-				}
-			} else if next == '(' {
-				if value, ok := Grammar.closure[stateTop].jumpTable['(']; ok {
-					//state change
-					state_stack = append(state_stack, value)
-					stack = append(stack, '(')
-					index++
-				}
-			} else if value, ok := Grammar.closure[stateTop].jumpTable[next]; ok {
-				//state change
-				state_stack = append(state_stack, value)
-				stack = append(stack, next)
-				index++
-			} else if Grammar.closure[stateTop].reduceAble {
-				//reduce
-				//find left
-				for _, runtoken := range Grammar.closure[stateTop].stateSet {
-					if runtoken.index == len(runtoken.token) {
-						if runtoken.left == 'Y' {
-							stack = stack[:len(stack)-len(runtoken.token)]
-							stack = append(stack, runtoken.left)
-							state_stack = state_stack[:len(state_stack)-len(runtoken.token)]
-						} else {
-							debugPrintf(level, "next step reduce use: ")
-							printRunToken(runtoken)
-							debugPrintf(level, "\n")
-							stack = stack[:len(stack)-len(runtoken.token)]
-							stack = append(stack, runtoken.left)
-							state_stack = state_stack[:len(state_stack)-len(runtoken.token)]
-						}
-					}
-				}
-			} else {
-				//error
-				return fmt.Errorf("can not accept next %c at state :%d", next, stateTop)
+		}
+		if *report != "" {
+			if err := writeFile(*report, Grammar.writeReport); err != nil {
+				log.Fatal(err)
 			}
 		}
-
-		step++
+		return
 	}
-}
-
-func main() {
-	grammar_filename := "../grammarlr0.txt"
-	Grammar := GrammarLR0{}
-	//read grammar
-	Grammar.buildGrammar(grammar_filename)
-	expression := "3*(2-1)"
-	err := Grammar.ParseExpression(expression)
-	if err != nil {
-		debugPrintf(ERROR, "Parse expression %s fail: %s\n", expression, err)
-	} else {
-		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
-	}
-	expression = "3+1/7+"
-	err = Grammar.ParseExpression(expression)
-	if err != nil {
-		debugPrintf(ERROR, "Parse expression %s fail: %s\n", expression, err)
-	} else {
-		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	parse := Grammar.parseAndPrint
+	if *glr {
+		parse = Grammar.parseGLRAndPrint
 	}
-	expression = "3+(*6"
-	err = Grammar.ParseExpression(expression)
-	if err != nil {
-		debugPrintf(ERROR, "Parse expression %s fail: %s\n", expression, err)
-	} else {
-		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	for _, expression := range []string{"3*(2-1)", "3+1/7+", "3+(*6"} {
+		parse(expression)
 	}
 	//read from stdin
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print("Enter expression: ")
-		expression, _ := reader.ReadString('\n')
+		expression, readErr := reader.ReadString('\n')
+		if readErr != nil && expression == "" {
+			break
+		}
 		expression = strings.Replace(expression, " ", "", -1)
 		expression = strings.Replace(expression, "\r", "", -1)
 		expression = strings.Replace(expression, "\n", "", -1)
-		err = Grammar.ParseExpression(expression)
-		if err != nil {
-			debugPrintf(ERROR, "Parse expression %s fail: %s\n", expression, err)
-		} else {
-			debugPrintf(ERROR, "Parse expression %s success.\n", expression)
-		}
+		parse(expression)
 	}
 
 }
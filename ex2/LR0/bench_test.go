@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// linearFindState is the state lookup findState replaced: the kernel is
+// closed and compared with every state, item by item.
+func linearFindState(Grammar *GrammarLR0, key string, kernel []RunToken) (int, []RunToken, bool) {
+	Grammar.__expandClosure(&kernel)
+	for index, node := range Grammar.closure {
+		if len(node.stateSet) == len(kernel) && containsItems(node.stateSet, kernel) {
+			return index, nil, true
+		}
+	}
+	return -1, kernel, false
+}
+
+func containsItems(stateSet []RunToken, items []RunToken) bool {
+	for _, item := range items {
+		found := false
+		for _, runtoken := range stateSet {
+			if runtoken.production == item.production && runtoken.index == item.index && bytes.Equal(runtoken.lookahead, item.lookahead) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// LOOKUPS are the state lookups the benchmarks compare.
+var LOOKUPS = []struct {
+	name   string
+	lookup func(Grammar *GrammarLR0, key string, kernel []RunToken) (int, []RunToken, bool)
+}{
+	{"hashed", (*GrammarLR0).findState},
+	{"linear", linearFindState},
+}
+
+// BenchmarkBuildCGrammar builds the automaton and table of the ANSI C grammar
+// in ../c.y with every construction method, looking the states up by hashed
+// kernel and by the linear search it replaced.
+func BenchmarkBuildCGrammar(b *testing.B) {
+	saved := debugLevel
+	debugLevel = ERROR
+	defer func() { debugLevel = saved }()
+	for _, method := range METHODS {
+		for _, lookup := range LOOKUPS {
+			b.Run(method+"/"+lookup.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					Grammar := GrammarLR0{lookup: lookup.lookup}
+					Grammar.buildGrammar("../c.y", method)
+				}
+			})
+		}
+	}
+}
+
+// TestLookupsBuildSameStates checks the benchmark compares like with like:
+// both lookups build the same number of states for the C grammar.
+func TestLookupsBuildSameStates(t *testing.T) {
+	saved := debugLevel
+	debugLevel = ERROR
+	defer func() { debugLevel = saved }()
+	for _, method := range METHODS {
+		states := make([]int, 0, len(LOOKUPS))
+		for _, lookup := range LOOKUPS {
+			Grammar := GrammarLR0{lookup: lookup.lookup}
+			Grammar.buildGrammar("../c.y", method)
+			states = append(states, len(Grammar.closure))
+		}
+		if states[0] != states[1] {
+			t.Errorf("%s: hashed lookup built %d states, linear %d", method, states[0], states[1])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"ex2/syntax"
+
+	"example.com/m/lexer"
+)
+
+// StackEntry is one entry of the LR parse stack, the state entered after
+// shifting or reducing to symbol and the semantic value of symbol. The
+// bottom entry is state 0 on '#'.
+type StackEntry struct {
+	state  int
+	symbol uint8
+	token  lexer.Token
+	value  interface{}
+}
+
+// printStep prints one line of the parse trace: step, state stack, symbol
+// stack, remaining input and the action taken.
+func printStep(step int, stack []StackEntry, tokens []lexer.Token, action string) {
+	level := INFO
+	states := make([]string, 0, len(stack))
+	symbols := make([]string, 0, len(stack))
+	for _, entry := range stack {
+		states = append(states, fmt.Sprint(entry.state))
+		symbols = append(symbols, string(entry.symbol))
+	}
+	input := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		input = append(input, token.Literal)
+	}
+	debugPrintf(level, "%-6d%-24s%-20s%-24s%s\n", step, strings.Join(states, " "),
+		strings.Join(symbols, ""), strings.Join(input, ""), action)
+}
+
+// ParseExpression lexes the expression with the ex1 lexer and parses the
+// resulting token stream.
+func (Grammar *GrammarLR0) ParseExpression(expression string) (interface{}, error) {
+	level := INFO
+	debugPrintf(level, "\nParseExpression  %s\n", expression)
+	return Grammar.ParseTokens(lexer.Lex([]byte(expression)))
+}
+
+// ParseTokens runs the ACTION/GOTO table over a token stream. A shift pushes
+// the next state with the token literal as value, a reduce pops one entry per
+// right hand side symbol, runs the semantic action of the production on their
+// values and pushes GOTO[top][left]. On accept the value of the start symbol
+// is returned.
+//
+// On an error entry the error is recorded with the expected terminals of the
+// state, then the stack is popped until a state can shift error, error is
+// shifted and input is discarded until a token has an action, as yacc does.
+// Until three tokens are shifted again no new error is reported. All errors
+// are returned together as syntax.Errors.
+func (Grammar *GrammarLR0) ParseTokens(tokens []lexer.Token) (interface{}, error) {
+	level := INFO
+	if !Grammar.ready {
+		debugPrintf(level, "Grammar not builded.\n")
+		return nil, errors.New("grammar not builded")
+	}
+	//add end symbol
+	end := lexer.Token{Type: lexer.EOF, Literal: "#"}
+	if len(tokens) > 0 {
+		end.Line = tokens[len(tokens)-1].Line
+		end.Column = tokens[len(tokens)-1].Column + 1
+	}
+	tokens = append(tokens, end)
+	debugPrintf(level, "%-6s%-24s%-20s%-24s%s\n", "step", "states", "symbols", "input", "action")
+	stack := []StackEntry{{state: 0, symbol: '#'}}
+	index := 0
+	syntaxErrors := make(syntax.Errors, 0)
+	//recovering counts the tokens still to shift before errors are reported
+	//again, 3 right after error was shifted
+	recovering := 0
+	for step := 0; ; step++ {
+		state := stack[len(stack)-1].state
+		token := tokens[index]
+		//a token the grammar does not know has no action anywhere
+		terminal, _ := syntax.TerminalOf(token)
+		action, ok := Grammar.table.action[state][terminal]
+		if !ok {
+			action = Action{kind: ACTION_ERROR}
+		}
+		switch action.kind {
+		case ACTION_SHIFT:
+			printStep(step, stack, tokens[index:], fmt.Sprintf("shift %d", action.target))
+			stack = append(stack, StackEntry{state: action.target, symbol: terminal, token: token, value: token.Literal})
+			index++
+			if recovering > 0 {
+				recovering--
+			}
+		case ACTION_REDUCE:
+			production := Grammar.unfoldGrammar[action.target]
+			printStep(step, stack, tokens[index:], fmt.Sprintf("reduce %c -> %s", production.left, production.right))
+			values := make([]interface{}, len(production.right))
+			for i, entry := range stack[len(stack)-len(production.right):] {
+				values[i] = entry.value
+			}
+			//after a syntax error the values are incomplete, actions are skipped
+			var value interface{}
+			var err error
+			if len(syntaxErrors) == 0 {
+				value, err = Grammar.reduceValue(production, values)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%c -> %s at line %d column %d: %s", production.left, production.right, token.Line, token.Column, err)
+			}
+			stack = stack[:len(stack)-len(production.right)]
+			target, ok := Grammar.table.goTo[stack[len(stack)-1].state][production.left]
+			if !ok {
+				return nil, fmt.Errorf("no goto on %c at state :%d", production.left, stack[len(stack)-1].state)
+			}
+			stack = append(stack, StackEntry{state: target, symbol: production.left, value: value})
+		case ACTION_ACCEPT:
+			printStep(step, stack, tokens[index:], "accept")
+			if len(syntaxErrors) > 0 {
+				return nil, syntaxErrors
+			}
+			return stack[len(stack)-1].value, nil
+		default:
+			if recovering == 3 {
+				//error was just shifted, discard the token
+				printStep(step, stack, tokens[index:], "discard")
+				if terminal == '#' {
+					return nil, syntaxErrors
+				}
+				index++
+				continue
+			}
+			printStep(step, stack, tokens[index:], "error")
+			if recovering == 0 {
+				syntaxErrors = append(syntaxErrors, syntax.Error{Token: token, State: state, Expected: Grammar.expectedTerminals(state)})
+				debugPrintf(ERROR, "Error: %s\n", syntaxErrors[len(syntaxErrors)-1])
+			}
+			var shift Action
+			stack, shift, ok = Grammar.errorState(stack)
+			if !ok {
+				return nil, syntaxErrors
+			}
+			stack = append(stack, StackEntry{state: shift.target, symbol: ERROR_SYMBOL})
+			recovering = 3
+		}
+	}
+}
+
+// parseAndPrint parses an expression and prints the value computed by the
+// semantic actions.
+func (Grammar *GrammarLR0) parseAndPrint(expression string) {
+	value, err := Grammar.ParseExpression(expression)
+	if err != nil {
+		debugPrintf(ERROR, "Parse expression %s fail: %s\n", expression, err)
+	} else if value != nil {
+		debugPrintf(ERROR, "Parse expression %s success, value %v.\n", expression, value)
+	} else {
+		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// symbolName is the name of a grammar symbol in exports: its name in a yacc
+// grammar, error for the error pseudo terminal, else the character itself.
+func (Grammar *GrammarLR0) symbolName(symbol uint8) string {
+	if name, ok := Grammar.symbolNames[symbol]; ok {
+		return name
+	}
+	if symbol == ERROR_SYMBOL {
+		return "error"
+	}
+	return string(symbol)
+}
+
+func (Grammar *GrammarLR0) sequenceName(sequence Token) string {
+	names := make([]string, 0, len(sequence))
+	for _, symbol := range sequence {
+		names = append(names, Grammar.symbolName(symbol))
+	}
+	return strings.Join(names, " ")
+}
+
+// itemName writes an item as E -> E + . T, followed by its lookaheads.
+func (Grammar *GrammarLR0) itemName(runtoken RunToken) string {
+	token := Grammar.itemRight(runtoken)
+	right := make([]string, 0, len(token)+1)
+	for i, symbol := range token {
+		if i == runtoken.index {
+			right = append(right, ".")
+		}
+		right = append(right, Grammar.symbolName(symbol))
+	}
+	if runtoken.index == len(token) {
+		right = append(right, ".")
+	}
+	item := fmt.Sprintf("%s -> %s", Grammar.symbolName(Grammar.itemLeft(runtoken)), strings.Join(right, " "))
+	if runtoken.lookahead != nil {
+		lookahead := make([]string, 0, len(runtoken.lookahead))
+		for _, symbol := range runtoken.lookahead {
+			lookahead = append(lookahead, Grammar.symbolName(symbol))
+		}
+		item += "  [" + strings.Join(lookahead, " ") + "]"
+	}
+	return item
+}
+
+// jumpSymbols returns the symbols a state has a transition on, terminals
+// first, each group sorted.
+func jumpSymbols(node Node) []uint8 {
+	terminals := make([]uint8, 0)
+	nonTerminals := make([]uint8, 0)
+	for symbol := range node.jumpTable {
+		if isNonTerminal(symbol) {
+			nonTerminals = append(nonTerminals, symbol)
+		} else {
+			terminals = append(terminals, symbol)
+		}
+	}
+	sortSymbols(terminals)
+	sortSymbols(nonTerminals)
+	return append(terminals, nonTerminals...)
+}
+
+func dotEscape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, `"`, `\"`, -1)
+}
+
+// exportDot writes the automaton as a Graphviz digraph. Each state is labeled
+// with its items, states with a completed item have a double border.
+func (Grammar *GrammarLR0) exportDot(w io.Writer) error {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "digraph %s {\n", Grammar.method)
+	buffer.WriteString("\trankdir=LR;\n")
+	buffer.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	for id, node := range Grammar.closure {
+		label := fmt.Sprintf("I%d\\l", id)
+		for _, runtoken := range node.stateSet {
+			label += dotEscape(Grammar.itemName(runtoken)) + "\\l"
+		}
+		peripheries := 1
+		if node.reduceAble {
+			peripheries = 2
+		}
+		fmt.Fprintf(&buffer, "\t%d [label=\"%s\", peripheries=%d];\n", id, label, peripheries)
+	}
+	for id, node := range Grammar.closure {
+		for _, symbol := range jumpSymbols(node) {
+			fmt.Fprintf(&buffer, "\t%d -> %d [label=\"%s\"];\n", id, node.jumpTable[symbol], dotEscape(Grammar.symbolName(symbol)))
+		}
+	}
+	buffer.WriteString("}\n")
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+func (Grammar *GrammarLR0) ruleName(production int) string {
+	rule := Grammar.unfoldGrammar[production]
+	right := Grammar.sequenceName(rule.right)
+	if right == "" {
+		right = "e"
+	}
+	return fmt.Sprintf("%s -> %s", Grammar.symbolName(rule.left), right)
+}
+
+func (Grammar *GrammarLR0) actionName(action Action) string {
+	switch action.kind {
+	case ACTION_SHIFT:
+		return fmt.Sprintf("shift, and go to state %d", action.target)
+	case ACTION_REDUCE:
+		return fmt.Sprintf("reduce using rule %d (%s)", action.target, Grammar.ruleName(action.target))
+	case ACTION_ACCEPT:
+		return "accept"
+	}
+	return "error (nonassociative)"
+}
+
+// writeReport writes a bison style report: the conflict summary, the rules,
+// and for every state its items, actions, gotos and conflicts.
+func (Grammar *GrammarLR0) writeReport(w io.Writer) error {
+	table := Grammar.table
+	var buffer bytes.Buffer
+	conflictCount := make(map[int][2]int)
+	for _, conflict := range table.conflicts {
+		count := conflictCount[conflict.state]
+		if conflict.isShiftReduce() {
+			count[0]++
+		} else {
+			count[1]++
+		}
+		conflictCount[conflict.state] = count
+	}
+	for state := range Grammar.closure {
+		if count, ok := conflictCount[state]; ok {
+			summary := make([]string, 0, 2)
+			if count[0] > 0 {
+				summary = append(summary, fmt.Sprintf("%d shift/reduce", count[0]))
+			}
+			if count[1] > 0 {
+				summary = append(summary, fmt.Sprintf("%d reduce/reduce", count[1]))
+			}
+			fmt.Fprintf(&buffer, "State %d conflicts: %s\n", state, strings.Join(summary, ", "))
+		}
+	}
+	if len(table.conflicts) > 0 {
+		buffer.WriteString("\n\n")
+	}
+
+	fmt.Fprintf(&buffer, "Grammar (%s)\n\n", table.method)
+	for id := range Grammar.unfoldGrammar {
+		fmt.Fprintf(&buffer, "%5d %s\n", id, Grammar.ruleName(id))
+	}
+	terminals := append([]uint8{}, Grammar.terminals...)
+	sortSymbols(terminals)
+	buffer.WriteString("\n\nTerminals\n\n")
+	for _, terminal := range terminals {
+		fmt.Fprintf(&buffer, "    %s\n", Grammar.symbolName(terminal))
+	}
+	buffer.WriteString("\n\nNonterminals\n\n")
+	for _, nonTerminal := range Grammar.nonTerminals {
+		fmt.Fprintf(&buffer, "    %s\n", Grammar.symbolName(nonTerminal))
+	}
+
+	for state, node := range Grammar.closure {
+		fmt.Fprintf(&buffer, "\n\nState %d\n\n", state)
+		for _, runtoken := range node.stateSet {
+			fmt.Fprintf(&buffer, "    %s\n", Grammar.itemName(runtoken))
+		}
+		buffer.WriteString("\n")
+		for _, kind := range []ActionType{ACTION_SHIFT, ACTION_ACCEPT, ACTION_REDUCE, ACTION_ERROR} {
+			for _, terminal := range terminals {
+				action, ok := table.action[state][terminal]
+				if ok && action.kind == kind {
+					fmt.Fprintf(&buffer, "    %-10s %s\n", Grammar.symbolName(terminal), Grammar.actionName(action))
+				}
+			}
+		}
+		gotos := false
+		for _, nonTerminal := range Grammar.nonTerminals {
+			if target, ok := table.goTo[state][nonTerminal]; ok {
+				if !gotos {
+					buffer.WriteString("\n")
+					gotos = true
+				}
+				fmt.Fprintf(&buffer, "    %-10s go to state %d\n", Grammar.symbolName(nonTerminal), target)
+			}
+		}
+		conflicts := false
+		for _, conflict := range table.conflicts {
+			if conflict.state != state {
+				continue
+			}
+			if !conflicts {
+				buffer.WriteString("\n")
+				conflicts = true
+			}
+			for _, action := range conflict.actions[1:] {
+				fmt.Fprintf(&buffer, "    %-10s [%s]\n", Grammar.symbolName(conflict.terminal), Grammar.actionName(action))
+			}
+		}
+		resolved := false
+		for _, resolution := range table.resolved {
+			if resolution.state != state {
+				continue
+			}
+			if !resolved {
+				buffer.WriteString("\n")
+				resolved = true
+			}
+			chosen := "an error"
+			if resolution.chosen.kind == ACTION_SHIFT {
+				chosen = "shift"
+			} else if resolution.chosen.kind == ACTION_REDUCE {
+				chosen = "reduce"
+			}
+			fmt.Fprintf(&buffer, "    Conflict between rule %d and token %s resolved as %s (%s).\n",
+				resolution.reduce.target, Grammar.symbolName(resolution.terminal), chosen, resolution.reason)
+		}
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// writeFile writes an export to a file, - is stdout.
+func writeFile(filename string, write func(w io.Writer) error) error {
+	if filename == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"ex2/syntax"
+
+	"example.com/m/lexer"
+)
+
+// GSSNode is a node of the graph structured stack: an LR state entered at an
+// input position. Its links point down the stack, one per way it was reached.
+type GSSNode struct {
+	state    int
+	position int
+	links    []*GSSLink
+}
+
+// GSSLink connects a GSS node to the node below it, labeled by the forest
+// node of the symbol between them.
+type GSSLink struct {
+	next *GSSNode
+	node *SPPFNode
+}
+
+// PackedNode is one way to derive a forest node: the production used and
+// the forest nodes of its right hand side.
+type PackedNode struct {
+	production int
+	children   []*SPPFNode
+}
+
+// SPPFNode is a node of the shared packed parse forest, a symbol deriving
+// input[start:end]. A terminal node holds its token, a non terminal node one
+// packed alternative per distinct derivation.
+type SPPFNode struct {
+	symbol       uint8
+	token        lexer.Token
+	start        int
+	end          int
+	alternatives []*PackedNode
+}
+
+// Forest is the result of a GLR parse, root derives the whole input.
+type Forest struct {
+	grammar *GrammarLR0
+	root    *SPPFNode
+}
+
+// ParseTree is one parse taken out of the forest.
+type ParseTree struct {
+	symbol     string
+	token      lexer.Token
+	production int
+	children   []*ParseTree
+}
+
+// Disambiguator decides whether an alternative of a forest node is kept.
+type Disambiguator func(node *SPPFNode, alternative *PackedNode) bool
+
+// String writes the tree as an S-expression, leaves as their literal.
+func (tree *ParseTree) String() string {
+	if tree.children == nil {
+		return tree.token.Literal
+	}
+	children := make([]string, 0, len(tree.children)+1)
+	children = append(children, tree.symbol)
+	for _, child := range tree.children {
+		children = append(children, child.String())
+	}
+	return "(" + strings.Join(children, " ") + ")"
+}
+
+// actionsOf returns every action of an ACTION entry, a conflict gives more
+// than one.
+func (table *LRTable) actionsOf(state int, terminal uint8) []Action {
+	action, ok := table.action[state][terminal]
+	if !ok || action.kind == ACTION_ERROR {
+		return nil
+	}
+	for _, conflict := range table.conflicts {
+		if conflict.state == state && conflict.terminal == terminal {
+			return conflict.actions
+		}
+	}
+	return []Action{action}
+}
+
+// paths lists the nodes reached by walking length links down from node,
+// with the forest nodes on the way in input order. When first is set the
+// walk must start with that link.
+func paths(node *GSSNode, length int, first *GSSLink) ([]*GSSNode, [][]*SPPFNode) {
+	if length == 0 {
+		return []*GSSNode{node}, [][]*SPPFNode{{}}
+	}
+	links := node.links
+	if first != nil {
+		links = []*GSSLink{first}
+	}
+	ends := make([]*GSSNode, 0)
+	children := make([][]*SPPFNode, 0)
+	for _, link := range links {
+		nextEnds, nextChildren := paths(link.next, length-1, nil)
+		for i := range nextEnds {
+			ends = append(ends, nextEnds[i])
+			children = append(children, append(nextChildren[i], link.node))
+		}
+	}
+	return ends, children
+}
+
+// addAlternative adds a derivation to a forest node unless it is there.
+func (node *SPPFNode) addAlternative(production int, children []*SPPFNode) {
+	for _, alternative := range node.alternatives {
+		if alternative.production != production || len(alternative.children) != len(children) {
+			continue
+		}
+		same := true
+		for i := range children {
+			if alternative.children[i] != children[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+	node.alternatives = append(node.alternatives, &PackedNode{production: production, children: children})
+}
+
+// ParseExpressionGLR lexes the expression and parses it with ParseGLR.
+func (Grammar *GrammarLR0) ParseExpressionGLR(expression string) (*Forest, error) {
+	level := INFO
+	debugPrintf(level, "\nParseExpressionGLR  %s\n", expression)
+	return Grammar.ParseGLR(lexer.Lex([]byte(expression)))
+}
+
+// ParseGLR parses a token stream with a GLR driver over the ACTION/GOTO
+// table: where an entry holds a conflict every action is taken, the stacks
+// share their nodes in a graph structured stack and the parses share their
+// nodes in a packed forest.
+func (Grammar *GrammarLR0) ParseGLR(tokens []lexer.Token) (*Forest, error) {
+	level := INFO
+	if !Grammar.ready {
+		debugPrintf(level, "Grammar not builded.\n")
+		return nil, errors.New("grammar not builded")
+	}
+	//add end symbol
+	end := lexer.Token{Type: lexer.EOF, Literal: "#"}
+	if len(tokens) > 0 {
+		end.Line = tokens[len(tokens)-1].Line
+		end.Column = tokens[len(tokens)-1].Column + 1
+	}
+	tokens = append(tokens, end)
+	bottom := &GSSNode{state: 0, position: 0}
+	frontier := []*GSSNode{bottom}
+	for position, token := range tokens {
+		terminal, ok := syntax.TerminalOf(token)
+		if !ok {
+			return nil, fmt.Errorf("unknown token %s at line %d column %d", token.Literal, token.Line, token.Column)
+		}
+		//forest nodes of non terminals ending here, shared by (symbol, start)
+		nodes := make(map[[2]int]*SPPFNode)
+		tops := make(map[int]*GSSNode)
+		for _, node := range frontier {
+			tops[node.state] = node
+		}
+		queue := append([]*GSSNode{}, frontier...)
+		handled := make(map[*GSSNode]bool)
+		//links added to handled tops, their reductions are redone through them
+		pending := make([]*GSSLink, 0)
+		pendingTop := make([]*GSSNode, 0)
+		reduce := func(node *GSSNode, production int, first *GSSLink) {
+			rule := Grammar.unfoldGrammar[production]
+			ends, children := paths(node, len(rule.right), first)
+			for i, base := range ends {
+				key := [2]int{int(rule.left), base.position}
+				forest, ok := nodes[key]
+				if !ok {
+					forest = &SPPFNode{symbol: rule.left, start: base.position, end: position}
+					nodes[key] = forest
+				}
+				forest.addAlternative(production, children[i])
+				state, ok := Grammar.table.goTo[base.state][rule.left]
+				if !ok {
+					continue
+				}
+				debugPrintf(DEBUG, "reduce %c -> %s from state %d to state %d\n", rule.left, rule.right, base.state, state)
+				top, ok := tops[state]
+				if !ok {
+					top = &GSSNode{state: state, position: position}
+					tops[state] = top
+					frontier = append(frontier, top)
+					queue = append(queue, top)
+				}
+				exists := false
+				for _, link := range top.links {
+					if link.next == base {
+						exists = true
+						break
+					}
+				}
+				if exists {
+					continue
+				}
+				link := &GSSLink{next: base, node: forest}
+				top.links = append(top.links, link)
+				if handled[top] {
+					pending = append(pending, link)
+					pendingTop = append(pendingTop, top)
+				}
+			}
+		}
+		for len(queue) > 0 || len(pending) > 0 {
+			if len(pending) > 0 {
+				top, link := pendingTop[0], pending[0]
+				pendingTop, pending = pendingTop[1:], pending[1:]
+				for _, action := range Grammar.table.actionsOf(top.state, terminal) {
+					if action.kind == ACTION_REDUCE && len(Grammar.unfoldGrammar[action.target].right) > 0 {
+						reduce(top, action.target, link)
+					}
+				}
+				continue
+			}
+			node := queue[0]
+			queue = queue[1:]
+			handled[node] = true
+			for _, action := range Grammar.table.actionsOf(node.state, terminal) {
+				if action.kind == ACTION_REDUCE {
+					reduce(node, action.target, nil)
+				}
+			}
+		}
+		if terminal == '#' {
+			for _, node := range frontier {
+				for _, action := range Grammar.table.actionsOf(node.state, terminal) {
+					if action.kind != ACTION_ACCEPT {
+						continue
+					}
+					for _, link := range node.links {
+						if link.next == bottom {
+							debugPrintf(level, "accept, %d parses\n", link.node.Count())
+							return &Forest{grammar: Grammar, root: link.node}, nil
+						}
+					}
+				}
+			}
+			return nil, Grammar.glrError(frontier, token)
+		}
+		//shift the token on every top that can
+		leaf := &SPPFNode{symbol: terminal, token: token, start: position, end: position + 1}
+		shifted := make(map[int]*GSSNode)
+		next := make([]*GSSNode, 0)
+		for _, node := range frontier {
+			for _, action := range Grammar.table.actionsOf(node.state, terminal) {
+				if action.kind != ACTION_SHIFT {
+					continue
+				}
+				top, ok := shifted[action.target]
+				if !ok {
+					top = &GSSNode{state: action.target, position: position + 1}
+					shifted[action.target] = top
+					next = append(next, top)
+				}
+				top.links = append(top.links, &GSSLink{next: node, node: leaf})
+			}
+		}
+		debugPrintf(DEBUG, "shift %s, %d stacks\n", token.Literal, len(next))
+		if len(next) == 0 {
+			return nil, Grammar.glrError(frontier, token)
+		}
+		frontier = next
+	}
+	return nil, errors.New("input ended without end marker")
+}
+
+// glrError reports the token no stack could take, with the terminals
+// expected by any of them.
+func (Grammar *GrammarLR0) glrError(frontier []*GSSNode, token lexer.Token) error {
+	expected := make([]uint8, 0)
+	for _, node := range frontier {
+		addSymbols(&expected, Grammar.expectedTerminals(node.state)...)
+	}
+	sortSymbols(expected)
+	state := 0
+	if len(frontier) > 0 {
+		state = frontier[0].state
+	}
+	return syntax.Errors{syntax.Error{Token: token, State: state, Expected: expected}}
+}
+
+// Count returns the number of parse trees below a forest node.
+func (node *SPPFNode) Count() int {
+	if node.alternatives == nil {
+		return 1
+	}
+	count := 0
+	for _, alternative := range node.alternatives {
+		product := 1
+		for _, child := range alternative.children {
+			product *= child.Count()
+		}
+		count += product
+	}
+	return count
+}
+
+// Trees lists every parse in the forest.
+func (forest *Forest) Trees() []*ParseTree {
+	return forest.trees(forest.root)
+}
+
+func (forest *Forest) trees(node *SPPFNode) []*ParseTree {
+	name := forest.grammar.symbolName(node.symbol)
+	if node.alternatives == nil && isTerminal(node.symbol) {
+		return []*ParseTree{{symbol: name, token: node.token, production: -1}}
+	}
+	result := make([]*ParseTree, 0)
+	for _, alternative := range node.alternatives {
+		//every combination of the trees of the children
+		combinations := [][]*ParseTree{{}}
+		for _, child := range alternative.children {
+			next := make([][]*ParseTree, 0)
+			for _, childTree := range forest.trees(child) {
+				for _, combination := range combinations {
+					next = append(next, append(append([]*ParseTree{}, combination...), childTree))
+				}
+			}
+			combinations = next
+		}
+		for _, children := range combinations {
+			result = append(result, &ParseTree{symbol: name, production: alternative.production, children: children})
+		}
+	}
+	return result
+}
+
+// Filter removes, everywhere in the forest, the alternatives a callback
+// rejects. Shared nodes are visited once.
+func (forest *Forest) Filter(keep ...Disambiguator) {
+	visited := make(map[*SPPFNode]bool)
+	var walk func(node *SPPFNode)
+	walk = func(node *SPPFNode) {
+		if visited[node] {
+			return
+		}
+		visited[node] = true
+		alternatives := make([]*PackedNode, 0, len(node.alternatives))
+		for _, alternative := range node.alternatives {
+			kept := true
+			for _, disambiguator := range keep {
+				if !disambiguator(node, alternative) {
+					kept = false
+					break
+				}
+			}
+			if kept {
+				alternatives = append(alternatives, alternative)
+			}
+		}
+		if node.alternatives != nil {
+			node.alternatives = alternatives
+		}
+		for _, alternative := range node.alternatives {
+			for _, child := range alternative.children {
+				walk(child)
+			}
+		}
+	}
+	walk(forest.root)
+}
+
+// binaryOperator returns the operator of an alternative X -> X op X.
+func (Grammar *GrammarLR0) binaryOperator(symbol uint8, alternative *PackedNode) (uint8, bool) {
+	right := Grammar.unfoldGrammar[alternative.production].right
+	if len(right) != 3 || right[0] != symbol || right[2] != symbol || isNonTerminal(right[1]) {
+		return 0, false
+	}
+	return right[1], true
+}
+
+// PrecedenceFilter is a Disambiguator for binary operator alternatives
+// X -> X op X: it rejects an alternative whose operand has an alternative
+// with an operator that should bind looser, using the %left, %right and
+// %nonassoc declarations of the grammar. Undeclared operators all share one
+// left associative level.
+func (Grammar *GrammarLR0) PrecedenceFilter(node *SPPFNode, alternative *PackedNode) bool {
+	operator, ok := Grammar.binaryOperator(node.symbol, alternative)
+	if !ok {
+		return true
+	}
+	outer := Grammar.precedence[operator]
+	for i, operand := range []*SPPFNode{alternative.children[0], alternative.children[2]} {
+		for _, inner := range operand.alternatives {
+			innerOperator, ok := Grammar.binaryOperator(operand.symbol, inner)
+			if !ok {
+				continue
+			}
+			level := Grammar.precedence[innerOperator].level
+			if level < outer.level {
+				return false
+			}
+			if level == outer.level {
+				//the left operand may only group on %right, the right one
+				//only otherwise
+				if (i == 0) == (outer.associativity != "right") {
+					continue
+				}
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseGLRAndPrint parses an expression with the GLR driver and prints every
+// parse, then the parses PrecedenceFilter keeps.
+func (Grammar *GrammarLR0) parseGLRAndPrint(expression string) {
+	forest, err := Grammar.ParseExpressionGLR(expression)
+	if err != nil {
+		debugPrintf(ERROR, "Parse expression %s fail: %s\n", expression, err)
+		return
+	}
+	debugPrintf(ERROR, "Parse expression %s success, %d parses.\n", expression, forest.root.Count())
+	for _, tree := range forest.Trees() {
+		debugPrintf(ERROR, "  %s\n", tree)
+	}
+	forest.Filter(Grammar.PrecedenceFilter)
+	debugPrintf(ERROR, "after precedence filter, %d parses.\n", forest.root.Count())
+	for _, tree := range forest.Trees() {
+		debugPrintf(ERROR, "  %s\n", tree)
+	}
+}
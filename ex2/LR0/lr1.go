@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sort"
+)
+
+// METHODS lists the table construction methods from the weakest to the
+// strongest.
+var METHODS = []string{"LR0", "SLR", "LALR", "LR1"}
+
+func sortSymbols(symbols []uint8) {
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+}
+
+// __expandLR1Closure computes the closure of a kernel of LR(1) items. An item
+// A -> w.Bv with lookahead L adds B -> .u with lookahead FIRST(vL); items with
+// the same core share one lookahead set, so the loop runs until no set grows.
+func (Grammar *GrammarLR0) __expandLR1Closure(stateSet *[]RunToken) {
+	level := DEBUG
+	debugPrint(level, "expandLR1Closure\n")
+	position := make(map[[2]int]int)
+	for i, runtoken := range *stateSet {
+		position[[2]int{runtoken.production, runtoken.index}] = i
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(*stateSet); i++ {
+			runtoken := (*stateSet)[i]
+			next, ok := Grammar.nextSymbol(runtoken)
+			if !ok || !isNonTerminal(next) {
+				continue
+			}
+			lookahead, nullable := Grammar.firstOfSequence(Grammar.itemRight(runtoken)[runtoken.index+1:])
+			if nullable {
+				addSymbols(&lookahead, runtoken.lookahead...)
+			}
+			for _, id := range Grammar.productionsOf[next] {
+				if p, ok := position[[2]int{id, 0}]; ok {
+					if addSymbols(&(*stateSet)[p].lookahead, lookahead...) {
+						changed = true
+					}
+					continue
+				}
+				item := Grammar.newRunToken(id)
+				item.lookahead = append([]uint8{}, lookahead...)
+				position[[2]int{id, 0}] = len(*stateSet)
+				*stateSet = append(*stateSet, item)
+				changed = true
+			}
+		}
+	}
+	for i := range *stateSet {
+		sortSymbols((*stateSet)[i].lookahead)
+	}
+}
+
+// mergeCores turns the canonical LR(1) collection into the LALR(1) one:
+// states with the same core are merged and their lookaheads united.
+func (Grammar *GrammarLR0) mergeCores() {
+	level := INFO
+	merged := make([]Node, 0)
+	groups := make(map[string]int)
+	groupOf := make([]int, len(Grammar.closure))
+	for id, node := range Grammar.closure {
+		key := itemSetKey(node.stateSet, false)
+		group, ok := groups[key]
+		if !ok {
+			group = len(merged)
+			groups[key] = group
+			stateSet := make([]RunToken, len(node.stateSet))
+			for i, runtoken := range node.stateSet {
+				stateSet[i] = runtoken
+				stateSet[i].lookahead = append([]uint8{}, runtoken.lookahead...)
+			}
+			merged = append(merged, Node{
+				id:         group,
+				stateSet:   stateSet,
+				jumpTable:  make(map[uint8]int),
+				reduceAble: node.reduceAble,
+			})
+		} else {
+			position := make(map[[2]int]int)
+			for i, item := range merged[group].stateSet {
+				position[[2]int{item.production, item.index}] = i
+			}
+			for _, runtoken := range node.stateSet {
+				item := &merged[group].stateSet[position[[2]int{runtoken.production, runtoken.index}]]
+				if addSymbols(&item.lookahead, runtoken.lookahead...) {
+					sortSymbols(item.lookahead)
+				}
+			}
+		}
+		groupOf[id] = group
+	}
+	for id, node := range Grammar.closure {
+		for symbol, target := range node.jumpTable {
+			merged[groupOf[id]].jumpTable[symbol] = groupOf[target]
+		}
+	}
+	debugPrintf(level, "merge %d LR(1) states into %d LALR(1) states\n", len(Grammar.closure), len(merged))
+	Grammar.closure = merged
+}
+
+// genLR0Table reduces a completed item on every terminal.
+func (Grammar *GrammarLR0) genLR0Table() *LRTable {
+	return Grammar.genTable("LR0", func(state int, runtoken RunToken) []uint8 {
+		return Grammar.terminals
+	})
+}
+
+// genLR1Table reduces a completed item on its own lookahead, used for both
+// the canonical LR(1) and the LALR(1) collection.
+func (Grammar *GrammarLR0) genLR1Table(method string) *LRTable {
+	return Grammar.genTable(method, func(state int, runtoken RunToken) []uint8 {
+		return runtoken.lookahead
+	})
+}
+
+// compareMethods builds the grammar with every method and prints the number
+// of states and conflicts of each.
+func compareMethods(grammar_filename string) {
+	level := ERROR
+	saved := debugLevel
+	debugLevel = ERROR
+	defer func() { debugLevel = saved }()
+	debugPrintf(level, "%-6s%8s%12s%16s%16s%12s\n", "method", "states", "conflicts", "shift/reduce", "reduce/reduce", "resolved")
+	for _, method := range METHODS {
+		Grammar := GrammarLR0{}
+		Grammar.buildGrammar(grammar_filename, method)
+		shiftReduce := 0
+		for _, conflict := range Grammar.table.conflicts {
+			if conflict.isShiftReduce() {
+				shiftReduce++
+			}
+		}
+		debugPrintf(level, "%-6s%8d%12d%16d%16d%12d\n", method, len(Grammar.closure), len(Grammar.table.conflicts),
+			shiftReduce, len(Grammar.table.conflicts)-shiftReduce, len(Grammar.table.resolved))
+	}
+}
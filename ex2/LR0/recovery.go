@@ -0,0 +1,31 @@
+package main
+
+// ERROR_SYMBOL is the error pseudo terminal, written error in the grammar
+// file. The driver shifts it when it recovers from a syntax error.
+const ERROR_SYMBOL uint8 = '@'
+
+// expectedTerminals lists, sorted, the terminals with an action in a state,
+// the error pseudo terminal left out.
+func (Grammar *GrammarLR0) expectedTerminals(state int) []uint8 {
+	expected := make([]uint8, 0)
+	for terminal, action := range Grammar.table.action[state] {
+		if terminal != ERROR_SYMBOL && action.kind != ACTION_ERROR {
+			expected = append(expected, terminal)
+		}
+	}
+	sortSymbols(expected)
+	return expected
+}
+
+// errorState pops the stack until the top state can shift error and returns
+// the shorter stack, or false when no state on the stack can.
+func (Grammar *GrammarLR0) errorState(stack []StackEntry) ([]StackEntry, Action, bool) {
+	for len(stack) > 0 {
+		action, ok := Grammar.table.action[stack[len(stack)-1].state][ERROR_SYMBOL]
+		if ok && action.kind == ACTION_SHIFT {
+			return stack, action, true
+		}
+		stack = stack[:len(stack)-1]
+	}
+	return stack, Action{}, false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SemanticAction computes the value of the left hand side of a reduction
+// from the values of the right hand side symbols, in order. A shifted
+// terminal has its token literal as value.
+type SemanticAction func(values []interface{}) (interface{}, error)
+
+// SEMANTIC_ACTIONS are registered in every grammar, they evaluate integer
+// expressions.
+var SEMANTIC_ACTIONS = map[string]SemanticAction{
+	"num": func(values []interface{}) (interface{}, error) {
+		literal, ok := values[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a literal", values[0])
+		}
+		return strconv.Atoi(literal)
+	},
+	"paren": func(values []interface{}) (interface{}, error) {
+		if len(values) != 3 {
+			return nil, errors.New("paren expects ( value )")
+		}
+		return values[1], nil
+	},
+	"add": binaryAction(func(a, b int) (int, error) { return a + b, nil }),
+	"sub": binaryAction(func(a, b int) (int, error) { return a - b, nil }),
+	"mul": binaryAction(func(a, b int) (int, error) { return a * b, nil }),
+	"div": binaryAction(func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	}),
+}
+
+// binaryAction wraps an integer operator as the action of a production
+// X -> Y op Z.
+func binaryAction(operator func(a, b int) (int, error)) SemanticAction {
+	return func(values []interface{}) (interface{}, error) {
+		if len(values) != 3 {
+			return nil, fmt.Errorf("binary action expects 3 values, got %d", len(values))
+		}
+		a, ok := values[0].(int)
+		b, ok2 := values[2].(int)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("operands %v and %v are not numbers", values[0], values[2])
+		}
+		return operator(a, b)
+	}
+}
+
+// RegisterAction adds or replaces the semantic action called name.
+func (Grammar *GrammarLR0) RegisterAction(name string, action SemanticAction) {
+	if Grammar.actions == nil {
+		Grammar.actions = make(map[string]SemanticAction)
+	}
+	Grammar.actions[name] = action
+}
+
+// reduceValue runs the semantic action of a production. A production without
+// action passes the value of its first symbol on, as yacc does with $$ = $1.
+func (Grammar *GrammarLR0) reduceValue(production Production, values []interface{}) (interface{}, error) {
+	if production.action == "" {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[0], nil
+	}
+	action, ok := Grammar.actions[production.action]
+	if !ok {
+		return nil, fmt.Errorf("unknown semantic action %s", production.action)
+	}
+	return action(values)
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"fmt"
+)
+
+type ActionType int
+
+const (
+	ACTION_ERROR ActionType = iota
+	ACTION_SHIFT
+	ACTION_REDUCE
+	ACTION_ACCEPT
+)
+
+// Action is one ACTION table entry, target is the state to shift to or the
+// production to reduce by.
+type Action struct {
+	kind   ActionType
+	target int
+}
+
+func (action Action) String() string {
+	switch action.kind {
+	case ACTION_SHIFT:
+		return fmt.Sprintf("s%d", action.target)
+	case ACTION_REDUCE:
+		return fmt.Sprintf("r%d", action.target)
+	case ACTION_ACCEPT:
+		return "acc"
+	}
+	return ""
+}
+
+// Conflict records the actions competing for one ACTION entry, the first
+// action is the one kept in the table.
+type Conflict struct {
+	state    int
+	terminal uint8
+	actions  []Action
+}
+
+func (conflict Conflict) isShiftReduce() bool {
+	for _, action := range conflict.actions {
+		if action.kind == ACTION_SHIFT {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolution records a shift/reduce conflict settled by precedence and
+// associativity instead of being reported.
+type Resolution struct {
+	state    int
+	terminal uint8
+	shift    Action
+	reduce   Action
+	chosen   Action
+	reason   string
+}
+
+// LRTable is the ACTION/GOTO table built from the LR automaton.
+type LRTable struct {
+	method    string
+	action    []map[uint8]Action
+	goTo      []map[uint8]int
+	conflicts []Conflict
+	resolved  []Resolution
+}
+
+// addSymbols adds symbols to a set and reports whether the set changed.
+func addSymbols(set *[]uint8, symbols ...uint8) bool {
+	changed := false
+	for _, symbol := range symbols {
+		found := false
+		for _, s := range *set {
+			if s == symbol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*set = append(*set, symbol)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// firstOfSequence returns FIRST of a sequence of symbols and whether the
+// whole sequence can derive the empty string.
+func (Grammar *GrammarLR0) firstOfSequence(sequence Token) ([]uint8, bool) {
+	first := make([]uint8, 0)
+	for _, symbol := range sequence {
+		if isTerminal(symbol) {
+			addSymbols(&first, symbol)
+			return first, false
+		}
+		addSymbols(&first, Grammar.first[symbol]...)
+		if !Grammar.nullable[symbol] {
+			return first, false
+		}
+	}
+	return first, true
+}
+
+// genFirst computes FIRST and the nullable set of every non terminal as a
+// fixed point, so left recursive productions are fine.
+func (Grammar *GrammarLR0) genFirst() {
+	Grammar.first = make(map[uint8]([]uint8))
+	Grammar.nullable = make(map[uint8]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, production := range Grammar.unfoldGrammar {
+			first, nullable := Grammar.firstOfSequence(production.right)
+			list := Grammar.first[production.left]
+			if addSymbols(&list, first...) {
+				changed = true
+			}
+			Grammar.first[production.left] = list
+			if nullable && !Grammar.nullable[production.left] {
+				Grammar.nullable[production.left] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// genFollow computes FOLLOW of every non terminal as a fixed point, FOLLOW of
+// the augmented start symbol is the end marker.
+func (Grammar *GrammarLR0) genFollow() {
+	Grammar.follow = make(map[uint8]([]uint8))
+	Grammar.follow[Grammar.augmentedStart] = []uint8{'#'}
+	for changed := true; changed; {
+		changed = false
+		for _, production := range Grammar.unfoldGrammar {
+			for i, symbol := range production.right {
+				if !isNonTerminal(symbol) {
+					continue
+				}
+				list := Grammar.follow[symbol]
+				first, nullable := Grammar.firstOfSequence(production.right[i+1:])
+				if addSymbols(&list, first...) {
+					changed = true
+				}
+				if nullable && addSymbols(&list, Grammar.follow[production.left]...) {
+					changed = true
+				}
+				Grammar.follow[symbol] = list
+			}
+		}
+	}
+}
+func (Grammar *GrammarLR0) printFirstFollow() {
+	level := INFO
+	debugPrintf(level, " %10s   %10s\n", "first", "follow")
+	for _, key := range Grammar.nonTerminals {
+		debugPrintf(level, "%c -> ", key)
+		firstStr := ""
+		followStr := ""
+		for _, token := range Grammar.first[key] {
+			firstStr += fmt.Sprintf("%c ", token)
+		}
+		if Grammar.nullable[key] {
+			firstStr += "e "
+		}
+		for _, token := range Grammar.follow[key] {
+			followStr += fmt.Sprintf("%c ", token)
+		}
+		debugPrintf(level, " %-10s  %-10s\n", firstStr, followStr)
+	}
+	debugPrint(level, "\n")
+}
+
+// productionPrecedence is the precedence of the terminal named by %prec,
+// else of the rightmost terminal of a production that has one.
+func (Grammar *GrammarLR0) productionPrecedence(production int) (Precedence, bool) {
+	if prec := Grammar.unfoldGrammar[production].prec; prec != 0 {
+		precedence, ok := Grammar.precedence[prec]
+		return precedence, ok
+	}
+	right := Grammar.unfoldGrammar[production].right
+	for i := len(right) - 1; i >= 0; i-- {
+		if precedence, ok := Grammar.precedence[right[i]]; ok {
+			return precedence, true
+		}
+	}
+	return Precedence{}, false
+}
+
+// resolveByPrecedence settles a shift/reduce conflict when both the terminal
+// and the production have a precedence: the higher one wins, on a tie %left
+// reduces, %right shifts and %nonassoc makes the entry an error.
+func (Grammar *GrammarLR0) resolveByPrecedence(terminal uint8, shift Action, reduce Action) (Action, string, bool) {
+	terminalPrecedence, ok := Grammar.precedence[terminal]
+	if !ok {
+		return Action{}, "", false
+	}
+	productionPrecedence, ok := Grammar.productionPrecedence(reduce.target)
+	if !ok {
+		return Action{}, "", false
+	}
+	if productionPrecedence.level > terminalPrecedence.level {
+		return reduce, "the production has higher precedence", true
+	}
+	if productionPrecedence.level < terminalPrecedence.level {
+		return shift, fmt.Sprintf("%c has higher precedence", terminal), true
+	}
+	switch terminalPrecedence.associativity {
+	case "left":
+		return reduce, fmt.Sprintf("%c is %%left", terminal), true
+	case "right":
+		return shift, fmt.Sprintf("%c is %%right", terminal), true
+	}
+	return Action{kind: ACTION_ERROR}, fmt.Sprintf("%c is %%nonassoc", terminal), true
+}
+
+// setAction fills one ACTION entry. A shift/reduce conflict is settled by
+// precedence when it can be, any other second, different action is recorded
+// as a conflict; shift is kept over reduce and the earlier production over
+// the later one, as yacc does.
+func (Grammar *GrammarLR0) setAction(table *LRTable, state int, terminal uint8, action Action) {
+	old, ok := table.action[state][terminal]
+	if !ok {
+		table.action[state][terminal] = action
+		return
+	}
+	if old == action {
+		return
+	}
+	if old.kind == ACTION_SHIFT && action.kind == ACTION_REDUCE {
+		if chosen, reason, ok := Grammar.resolveByPrecedence(terminal, old, action); ok {
+			table.action[state][terminal] = chosen
+			table.resolved = append(table.resolved, Resolution{
+				state:    state,
+				terminal: terminal,
+				shift:    old,
+				reduce:   action,
+				chosen:   chosen,
+				reason:   reason,
+			})
+			return
+		}
+	}
+	for i := range table.conflicts {
+		conflict := &table.conflicts[i]
+		if conflict.state == state && conflict.terminal == terminal {
+			for _, a := range conflict.actions {
+				if a == action {
+					return
+				}
+			}
+			conflict.actions = append(conflict.actions, action)
+			return
+		}
+	}
+	keep, drop := old, action
+	if action.kind == ACTION_SHIFT || (old.kind == ACTION_REDUCE && action.kind == ACTION_REDUCE && action.target < old.target) {
+		keep, drop = action, old
+	}
+	table.action[state][terminal] = keep
+	table.conflicts = append(table.conflicts, Conflict{state: state, terminal: terminal, actions: []Action{keep, drop}})
+}
+
+// genTable builds the ACTION/GOTO table from the automaton. lookahead
+// returns the terminals on which a completed item of a state is reduced.
+func (Grammar *GrammarLR0) genTable(method string, lookahead func(state int, runtoken RunToken) []uint8) *LRTable {
+	table := &LRTable{
+		method:    method,
+		action:    make([]map[uint8]Action, len(Grammar.closure)),
+		goTo:      make([]map[uint8]int, len(Grammar.closure)),
+		conflicts: make([]Conflict, 0),
+		resolved:  make([]Resolution, 0),
+	}
+	for state, node := range Grammar.closure {
+		table.action[state] = make(map[uint8]Action)
+		table.goTo[state] = make(map[uint8]int)
+		for _, symbol := range Grammar.terminals {
+			if next, ok := node.jumpTable[symbol]; ok {
+				Grammar.setAction(table, state, symbol, Action{kind: ACTION_SHIFT, target: next})
+			}
+		}
+		for _, symbol := range Grammar.nonTerminals {
+			if next, ok := node.jumpTable[symbol]; ok {
+				table.goTo[state][symbol] = next
+			}
+		}
+		for _, runtoken := range node.stateSet {
+			if _, ok := Grammar.nextSymbol(runtoken); ok {
+				continue
+			}
+			if runtoken.production == 0 {
+				Grammar.setAction(table, state, '#', Action{kind: ACTION_ACCEPT})
+				continue
+			}
+			for _, terminal := range lookahead(state, runtoken) {
+				Grammar.setAction(table, state, terminal, Action{kind: ACTION_REDUCE, target: runtoken.production})
+			}
+		}
+	}
+	return table
+}
+
+// genSLRTable reduces a completed item A -> w. on the terminals of FOLLOW(A).
+func (Grammar *GrammarLR0) genSLRTable() *LRTable {
+	return Grammar.genTable("SLR", func(state int, runtoken RunToken) []uint8 {
+		return Grammar.follow[Grammar.itemLeft(runtoken)]
+	})
+}
+
+func (Grammar *GrammarLR0) printTable(table *LRTable) {
+	Grammar.printActionGoto(table)
+	Grammar.printResolved(table)
+	Grammar.printConflicts(table)
+}
+func (Grammar *GrammarLR0) printActionGoto(table *LRTable) {
+	level := INFO
+	if level < debugLevel {
+		return
+	}
+	debugPrintf(level, "%s table\n", table.method)
+	title := "state "
+	for _, key := range Grammar.terminals {
+		title += fmt.Sprintf("%5c", key)
+	}
+	for _, key := range Grammar.nonTerminals {
+		if key != Grammar.augmentedStart {
+			title += fmt.Sprintf("%5c", key)
+		}
+	}
+	debugPrintf(level, "%s\n", title)
+	for state := range table.action {
+		txt := fmt.Sprintf("%-6d", state)
+		for _, key := range Grammar.terminals {
+			txt += fmt.Sprintf("%5s", table.action[state][key])
+		}
+		for _, key := range Grammar.nonTerminals {
+			if key == Grammar.augmentedStart {
+				continue
+			}
+			if value, ok := table.goTo[state][key]; ok {
+				txt += fmt.Sprintf("%5d", value)
+			} else {
+				txt += fmt.Sprintf("%5s", "")
+			}
+		}
+		debugPrintf(level, "%s\n", txt)
+	}
+	debugPrint(level, "\n")
+}
+
+// printResolved lists the conflicts settled by precedence declarations.
+func (Grammar *GrammarLR0) printResolved(table *LRTable) {
+	level := INFO
+	for _, resolution := range table.resolved {
+		production := Grammar.unfoldGrammar[resolution.reduce.target]
+		chosen := "error"
+		if resolution.chosen.kind == ACTION_SHIFT {
+			chosen = "shift"
+		} else if resolution.chosen.kind == ACTION_REDUCE {
+			chosen = "reduce"
+		}
+		debugPrintf(level, "state %d on %c: %s between %s and %s(%c -> %s), %s\n",
+			resolution.state, resolution.terminal, chosen, resolution.shift, resolution.reduce,
+			production.left, production.right, resolution.reason)
+	}
+	if len(table.resolved) > 0 {
+		debugPrintf(level, "%s table settled %d conflicts by precedence\n\n", table.method, len(table.resolved))
+	}
+}
+
+// printConflicts lists every conflict of the table with the items of its
+// state.
+func (Grammar *GrammarLR0) printConflicts(table *LRTable) {
+	level := WARNNING
+	for _, conflict := range table.conflicts {
+		kind := "reduce/reduce"
+		if conflict.isShiftReduce() {
+			kind = "shift/reduce"
+		}
+		debugPrintf(level, "%s conflict in state %d on %c:", kind, conflict.state, conflict.terminal)
+		for _, action := range conflict.actions {
+			debugPrintf(level, " %s", action)
+			if action.kind == ACTION_REDUCE {
+				production := Grammar.unfoldGrammar[action.target]
+				debugPrintf(level, "(%c -> %s)", production.left, production.right)
+			}
+		}
+		debugPrint(level, "\n")
+		Grammar.printStateSet(Grammar.closure[conflict.state].stateSet)
+	}
+	if len(table.conflicts) > 0 {
+		debugPrintf(level, "%s table has %d conflicts\n\n", table.method, len(table.conflicts))
+	}
+}
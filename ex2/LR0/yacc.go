@@ -0,0 +1,274 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// NONTERMINAL_BASE is the first byte given to the named non terminals of a
+// yacc grammar, so isNonTerminal holds for 'A'-'Z' and every byte from here
+// up. Named terminals get the free bytes below it.
+const NONTERMINAL_BASE = 0x80
+
+// yaccWords splits a yacc grammar into words: names, 'c' literals, { }
+// action blocks, %keywords and the punctuation : | ;. Comments are dropped.
+// An action block ends at the brace matching its first one, braces within
+// literals and comments left out.
+func yaccWords(s string) []string {
+	words := make([]string, 0)
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			i++
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+			i += end + 4
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				log.Print("Error: grammar file format error, unterminated literal")
+				os.Exit(1)
+			}
+			words = append(words, s[i:i+end+2])
+			i += end + 2
+		case ch == '{':
+			end := actionEnd(s, i)
+			if end < 0 {
+				log.Print("Error: grammar file format error, unterminated action")
+				os.Exit(1)
+			}
+			words = append(words, s[i:end])
+			i = end
+		case ch == '%':
+			j := i + 1
+			if j < len(s) && s[j] == '%' {
+				j++
+			}
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			words = append(words, s[i:j])
+			i = j
+		case isNameChar(ch):
+			j := i
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			words = append(words, s[i:j])
+			i = j
+		default:
+			words = append(words, string(ch))
+			i++
+		}
+	}
+	return words
+}
+
+// actionEnd is the index after the brace closing the action block opened at
+// begin, or -1 when it is not closed.
+func actionEnd(s string, begin int) int {
+	depth := 0
+	for i := begin; i < len(s); i++ {
+		switch {
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case s[i] == '\'' || s[i] == '"':
+			//skip the literal, escapes included
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return -1
+			}
+			i += end + 3
+		}
+	}
+	return -1
+}
+
+func isNameChar(ch byte) bool {
+	return ch == '_' || ch == '.' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+func isLiteral(word string) bool {
+	return len(word) == 3 && word[0] == '\'' && word[2] == '\''
+}
+
+// readYaccGrammar reads a grammar written as a yacc .y file, with named
+// symbols. Every non terminal gets a byte from NONTERMINAL_BASE up, a 'c'
+// literal stands for itself and every other name gets a free byte below
+// NONTERMINAL_BASE. The names are kept in Grammar.symbolNames.
+func (Grammar *GrammarLR0) readYaccGrammar(grammar_filename string) {
+	b, err := ioutil.ReadFile(grammar_filename)
+	if err != nil {
+		log.Print(err)
+	}
+	sections := strings.SplitN(string(b), "%%", 3)
+	if len(sections) < 2 {
+		log.Print("Error: grammar file format error, missing %%")
+		os.Exit(1)
+	}
+	declarations := yaccWords(sections[0])
+	rules := yaccWords(sections[1])
+
+	//non terminals are the names on the left of a rule, in file order
+	symbols := make(map[string]uint8)
+	used := map[uint8]bool{'#': true, '@': true, 'e': true}
+	for i, word := range rules {
+		if word == ":" && i > 0 {
+			if _, ok := symbols[rules[i-1]]; !ok {
+				if len(Grammar.nonTerminals) == 256-NONTERMINAL_BASE {
+					log.Print("Error: too many non terminals")
+					os.Exit(1)
+				}
+				symbol := uint8(NONTERMINAL_BASE + len(Grammar.nonTerminals))
+				symbols[rules[i-1]] = symbol
+				Grammar.symbolNames[symbol] = rules[i-1]
+				Grammar.grammar[symbol] = make([]Token, 0)
+				Grammar.semantic[symbol] = make([]string, 0)
+				Grammar.prec[symbol] = make([]uint8, 0)
+				Grammar.nonTerminals = append(Grammar.nonTerminals, symbol)
+			}
+		}
+	}
+	for _, word := range append(declarations, rules...) {
+		if isLiteral(word) {
+			used[word[1]] = true
+		}
+	}
+	free := uint8(1)
+	symbolOf := func(word string) uint8 {
+		if symbol, ok := symbols[word]; ok {
+			return symbol
+		}
+		if word == "error" {
+			return ERROR_SYMBOL
+		}
+		if isLiteral(word) {
+			symbol := word[1]
+			if isNonTerminal(symbol) || symbol == 'e' || symbol == '#' || symbol == '@' {
+				log.Printf("Error: literal %s can not be used as a terminal", word)
+				os.Exit(1)
+			}
+			symbols[word] = symbol
+			Grammar.symbolNames[symbol] = word
+			return symbol
+		}
+		for ; free < NONTERMINAL_BASE && (used[free] || isNonTerminal(free)); free++ {
+		}
+		if free == NONTERMINAL_BASE {
+			log.Print("Error: too many terminals")
+			os.Exit(1)
+		}
+		symbols[word] = free
+		Grammar.symbolNames[free] = word
+		used[free] = true
+		return free
+	}
+
+	for i := 0; i < len(declarations); i++ {
+		switch declarations[i] {
+		case "%token":
+			for i+1 < len(declarations) && !strings.HasPrefix(declarations[i+1], "%") {
+				i++
+				symbolOf(declarations[i])
+			}
+		case "%left", "%right", "%nonassoc":
+			Grammar.precedenceLevels++
+			associativity := declarations[i][1:]
+			for i+1 < len(declarations) && !strings.HasPrefix(declarations[i+1], "%") {
+				i++
+				Grammar.precedence[symbolOf(declarations[i])] = Precedence{
+					level:         Grammar.precedenceLevels,
+					associativity: associativity,
+				}
+			}
+		case "%start":
+			i++
+			if i == len(declarations) {
+				log.Print("Error: grammar file format error, start without symbol")
+				os.Exit(1)
+			}
+			start, ok := symbols[declarations[i]]
+			if !ok {
+				log.Printf("Error: start symbol %s has no rule", declarations[i])
+				os.Exit(1)
+			}
+			Grammar.start = start
+		}
+	}
+	if len(Grammar.nonTerminals) == 0 {
+		log.Print("Error: grammar has no rule")
+		os.Exit(1)
+	}
+	if Grammar.start == 0 {
+		Grammar.start = Grammar.nonTerminals[0]
+	}
+
+	//rules: name : alternative | alternative ; where an alternative may end
+	//with %prec terminal
+	for i := 0; i+1 < len(rules); {
+		if rules[i+1] != ":" {
+			log.Printf("Error: grammar file format error near %s", rules[i])
+			os.Exit(1)
+		}
+		key := symbols[rules[i]]
+		right := make(Token, 0)
+		action := ""
+		var prec uint8
+		for i += 2; ; i++ {
+			if i == len(rules) {
+				log.Print("Error: grammar file format error, missing ;")
+				os.Exit(1)
+			}
+			word := rules[i]
+			if word == "|" || word == ";" {
+				Grammar.grammar[key] = append(Grammar.grammar[key], right)
+				Grammar.semantic[key] = append(Grammar.semantic[key], action)
+				Grammar.prec[key] = append(Grammar.prec[key], prec)
+				right = make(Token, 0)
+				action = ""
+				prec = 0
+				if word == ";" {
+					i++
+					break
+				}
+			} else if word == "%prec" {
+				i++
+				if i == len(rules) || rules[i] == "|" || rules[i] == ";" {
+					log.Printf("Error: grammar file format error, %%prec without terminal")
+					os.Exit(1)
+				}
+				prec = symbolOf(rules[i])
+				if _, ok := Grammar.precedence[prec]; !ok {
+					log.Printf("Error: %%prec %s has no precedence", rules[i])
+					os.Exit(1)
+				}
+			} else if strings.HasPrefix(word, "%") {
+				log.Printf("Error: %s is not supported in a rule", word)
+				os.Exit(1)
+			} else if strings.HasPrefix(word, "{") {
+				action = strings.TrimSpace(word[1 : len(word)-1])
+			} else {
+				right = append(right, symbolOf(word))
+			}
+		}
+	}
+	Grammar.printGrammar()
+}
@@ -1,3 +1,7 @@
 module ex2
 
 go 1.16
+
+require example.com/m v0.0.0
+
+replace example.com/m => ../ex1
@@ -0,0 +1,77 @@
+// Package syntax holds what the LL(1) and LR drivers share: the terminals of
+// the grammar files and the syntax errors the drivers report.
+package syntax
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/m/lexer"
+)
+
+// TERMINALCAST maps the single character terminals of the grammar file to
+// the token types produced by the ex1 lexer.
+var TERMINALCAST = map[uint8]lexer.TokenType{
+	'n': lexer.NUMBER,
+	'i': lexer.IDENTIFIER,
+	'+': lexer.PLUS,
+	'-': lexer.MINUS,
+	'*': lexer.MUL,
+	'/': lexer.DIV,
+	'%': lexer.MOD,
+	'(': lexer.LPAREN,
+	')': lexer.RPAREN,
+	'=': lexer.ASSIGN,
+	';': lexer.SEMICOLON,
+	',': lexer.COMMA,
+	'<': lexer.LT,
+	'>': lexer.GT,
+	'!': lexer.NOT,
+	'#': lexer.EOF,
+}
+
+// TerminalOf returns the grammar terminal matching the type of a lexer token.
+func TerminalOf(token lexer.Token) (uint8, bool) {
+	for terminal, tokenType := range TERMINALCAST {
+		if tokenType == token.Type {
+			return terminal, true
+		}
+	}
+	return 0, false
+}
+
+// NO_STATE is the State of an error found by a driver without LR states.
+const NO_STATE = -1
+
+// Error is one syntax error reported by a driver, with the terminals that
+// would have been accepted at that point and, for an LR driver, the state
+// where it was found.
+type Error struct {
+	Token    lexer.Token
+	State    int
+	Expected []uint8
+}
+
+func (err Error) Error() string {
+	expected := make([]string, 0, len(err.Expected))
+	for _, terminal := range err.Expected {
+		expected = append(expected, string(terminal))
+	}
+	at := ""
+	if err.State != NO_STATE {
+		at = fmt.Sprintf(" at state %d", err.State)
+	}
+	return fmt.Sprintf("line %d column %d: unexpected %s%s, expected one of [%s]",
+		err.Token.Line, err.Token.Column, err.Token.Literal, at, strings.Join(expected, " "))
+}
+
+// Errors collects every error found while parsing one input.
+type Errors []Error
+
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
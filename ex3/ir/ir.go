@@ -0,0 +1,212 @@
+// Package ir is the quadruple intermediate representation produced by the
+// translator in ex3 and consumed by the code generator in ex4.
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type OperandKind int
+
+const (
+	NONE OperandKind = iota
+	CONST
+	VAR
+	TEMP
+	LABEL
+	FUNC
+	STRING
+)
+
+// Operand is a tagged union of a constant, a named variable, a temporary, a
+// label, a function and a string literal. Value holds the constant or the
+// number of the temporary or label, Name the name of the variable or
+// function or the quoted literal.
+type Operand struct {
+	Kind  OperandKind
+	Value int
+	Name  string
+}
+
+func Const(value int) Operand {
+	return Operand{Kind: CONST, Value: value}
+}
+func Var(name string) Operand {
+	return Operand{Kind: VAR, Name: name}
+}
+func Temp(number int) Operand {
+	return Operand{Kind: TEMP, Value: number}
+}
+func Label(number int) Operand {
+	return Operand{Kind: LABEL, Value: number}
+}
+func Func(name string) Operand {
+	return Operand{Kind: FUNC, Name: name}
+}
+func Str(literal string) Operand {
+	return Operand{Kind: STRING, Name: literal}
+}
+
+func (operand Operand) IsConst() bool {
+	return operand.Kind == CONST
+}
+func (operand Operand) IsVar() bool {
+	return operand.Kind == VAR
+}
+func (operand Operand) IsTemp() bool {
+	return operand.Kind == TEMP
+}
+func (operand Operand) IsLabel() bool {
+	return operand.Kind == LABEL
+}
+func (operand Operand) IsFunc() bool {
+	return operand.Kind == FUNC
+}
+func (operand Operand) IsString() bool {
+	return operand.Kind == STRING
+}
+
+// String writes a constant as its value, a variable as its name, temporaries
+// as t0, t1, ..., labels as L0, L1, ... and no operand as _.
+func (operand Operand) String() string {
+	switch operand.Kind {
+	case CONST:
+		return strconv.Itoa(operand.Value)
+	case VAR, FUNC, STRING:
+		return operand.Name
+	case TEMP:
+		return fmt.Sprintf("t%d", operand.Value)
+	case LABEL:
+		return fmt.Sprintf("L%d", operand.Value)
+	}
+	return "_"
+}
+
+// Quad is one quadruple: Result = Arg1 Op Arg2, the operators being + - * /
+// % << >> & | ^, or Result = Op Arg1 for neg and ~, or Result = Arg1 for =.
+// A label quad places the label Result, a jump quad jumps to the label
+// Result: j always, jnz when Arg1 is not zero and j< j> j<= j>= j== j!= when
+// Arg1 compares so to Arg2.
+//
+// A function is the quads from (func, _, _, f) to (endfunc, _, _, f). Its
+// parameters are declared by (formal, i, _, x), x being the i-th from 0, its
+// local variables by (local, _, _, x), other variables are global. A call
+// of f with n arguments is n quads (param, a, _, _) in order followed by
+// (call, f, n, t), t receives the value returned by (return, v, _, _).
+type Quad struct {
+	Op     string
+	Arg1   Operand
+	Arg2   Operand
+	Result Operand
+}
+
+const (
+	LABEL_OP   = "label"
+	FUNC_OP    = "func"
+	ENDFUNC_OP = "endfunc"
+	FORMAL_OP  = "formal"
+	LOCAL_OP   = "local"
+	PARAM_OP   = "param"
+	CALL_OP    = "call"
+	RETURN_OP  = "return"
+)
+
+func (quad Quad) IsLabel() bool {
+	return quad.Op == LABEL_OP
+}
+func (quad Quad) IsJump() bool {
+	return strings.HasPrefix(quad.Op, "j")
+}
+
+func (quad Quad) String() string {
+	return fmt.Sprintf("(%s, %s, %s, %s)", quad.Op, quad.Arg1, quad.Arg2, quad.Result)
+}
+
+// Builder collects the quads of one translation and hands out fresh
+// temporaries and labels, there is no limit on their number.
+type Builder struct {
+	Quads  []Quad
+	temps  int
+	labels int
+}
+
+func NewBuilder() *Builder {
+	return &Builder{Quads: make([]Quad, 0)}
+}
+
+func (builder *Builder) NewTemp() Operand {
+	builder.temps++
+	return Temp(builder.temps - 1)
+}
+func (builder *Builder) NewLabel() Operand {
+	builder.labels++
+	return Label(builder.labels - 1)
+}
+
+// Emit appends a quad and returns its index.
+func (builder *Builder) Emit(op string, arg1 Operand, arg2 Operand, result Operand) int {
+	builder.Quads = append(builder.Quads, Quad{Op: op, Arg1: arg1, Arg2: arg2, Result: result})
+	return len(builder.Quads) - 1
+}
+
+// PlaceLabel emits a new label at the current position and returns it.
+func (builder *Builder) PlaceLabel() Operand {
+	label := builder.NewLabel()
+	builder.Emit(LABEL_OP, Operand{}, Operand{}, label)
+	return label
+}
+
+// Backpatch makes the jumps at the indexes in list jump to label.
+func (builder *Builder) Backpatch(list []int, label Operand) {
+	for _, i := range list {
+		builder.Quads[i].Result = label
+	}
+}
+
+// Print writes the quads numbered, one per line, a label as L0: on its own.
+func Print(w io.Writer, quads []Quad) error {
+	for i, quad := range quads {
+		var err error
+		if quad.IsLabel() {
+			_, err = fmt.Fprintf(w, "%s:\n", quad.Result)
+		} else {
+			_, err = fmt.Fprintf(w, "%3d %s\n", i, quad)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Storage lists, in order of first use, the variables and temporaries the
+// quads read or write, each once.
+func Storage(quads []Quad) []Operand {
+	seen := make(map[Operand]bool)
+	storage := make([]Operand, 0)
+	for _, quad := range quads {
+		for _, operand := range []Operand{quad.Arg1, quad.Arg2, quad.Result} {
+			if (operand.IsVar() || operand.IsTemp()) && !seen[operand] {
+				seen[operand] = true
+				storage = append(storage, operand)
+			}
+		}
+	}
+	return storage
+}
+
+// Equal tells whether two lists hold the same quads in the same order.
+func Equal(a []Quad, b []Quad) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,831 @@
+// Package ll1 is the LL(1) driver of the translation: it builds the parse
+// table of a grammar file with action symbols and translates programs into
+// quads, which a Backend may go on to compile.
+package ll1
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"ex3/ir"
+	"ex3/opt"
+	"ex3/sdt"
+
+	"example.com/m/lexer"
+)
+
+// CHARCAST maps names written in the grammar file to the single character
+// symbols the grammar is built on.
+var CHARCAST = map[string]uint8{
+	`E'`: 'R',
+	`T'`: 'Y',
+	//the letters are all taken, more primed names get the bytes from
+	//NONTERMINAL_BASE
+	`A'`:  NONTERMINAL_BASE,
+	`H'`:  NONTERMINAL_BASE + 1,
+	`N'`:  NONTERMINAL_BASE + 2,
+	`Q'`:  NONTERMINAL_BASE + 3,
+	`S'`:  NONTERMINAL_BASE + 4,
+	`U'`:  NONTERMINAL_BASE + 5,
+	`V'`:  NONTERMINAL_BASE + 6,
+	`W'`:  NONTERMINAL_BASE + 7,
+	`X'`:  NONTERMINAL_BASE + 8,
+	`Y'`:  NONTERMINAL_BASE + 9,
+	`Z'`:  NONTERMINAL_BASE + 10,
+	"&&":  'a',
+	"||":  'o',
+	"==":  'q',
+	"!=":  'u',
+	"<=":  'l',
+	">=":  'g',
+	"<<":  'h',
+	">>":  'j',
+	"'|'": 'p',
+	//compound assignments
+	"+=":  '0',
+	"-=":  '1',
+	"*=":  '2',
+	"/=":  '3',
+	"%=":  '4',
+	"&=":  '5',
+	"|=":  '6',
+	"^=":  '7',
+	"<<=": '8',
+	">>=": '9',
+	//keywords and braces, a bare { starts an action symbol
+	"if":       'f',
+	"else":     'z',
+	"while":    'w',
+	"do":       'd',
+	"for":      'r',
+	"break":    'k',
+	"continue": 'c',
+	"return":   'x',
+	"int":      't',
+	"void":     'v',
+	"'{'":      '[',
+	"'}'":      ']',
+}
+
+// TERMINALCAST maps the single character terminals of the grammar file to
+// the token types produced by the ex1 lexer.
+var TERMINALCAST = map[uint8]lexer.TokenType{
+	'n': lexer.NUMBER,
+	'i': lexer.IDENTIFIER,
+	'+': lexer.PLUS,
+	'-': lexer.MINUS,
+	'*': lexer.MUL,
+	'/': lexer.DIV,
+	'%': lexer.MOD,
+	'~': lexer.BITNOT,
+	'&': lexer.BITAND,
+	'^': lexer.BITXOR,
+	'p': lexer.BITOR,
+	'h': lexer.BITLSHIFT,
+	'j': lexer.BITRSHIFT,
+	'0': lexer.PLUSASSIGN,
+	'1': lexer.MINUSASSIGN,
+	'2': lexer.MULASSIGN,
+	'3': lexer.DIVASSIGN,
+	'4': lexer.MODASSIGN,
+	'5': lexer.ANDASSIGN,
+	'6': lexer.ORASSIGN,
+	'7': lexer.XORASSIGN,
+	'8': lexer.LSHIFTASSIGN,
+	'9': lexer.RSHIFTASSIGN,
+	'(': lexer.LPAREN,
+	')': lexer.RPAREN,
+	'=': lexer.ASSIGN,
+	';': lexer.SEMICOLON,
+	'<': lexer.LT,
+	'>': lexer.GT,
+	'l': lexer.LEQ,
+	'g': lexer.GEQ,
+	'q': lexer.EQ,
+	'u': lexer.NEQ,
+	'a': lexer.AND,
+	'o': lexer.OR,
+	'!': lexer.NOT,
+	'f': lexer.IF,
+	'z': lexer.ELSE,
+	'w': lexer.WHILE,
+	'd': lexer.DO,
+	'r': lexer.FOR,
+	'k': lexer.BREAK,
+	'c': lexer.CONTINUE,
+	'x': lexer.RETURN,
+	't': lexer.INT,
+	'v': lexer.VOID,
+	's': lexer.STRING,
+	',': lexer.COMMA,
+	'[': lexer.LBRACE,
+	']': lexer.RBRACE,
+	'#': lexer.EOF,
+}
+
+// NONTERMINAL_BASE is the first byte of the non terminals beyond 'A'-'Z',
+// below the bytes of the action symbols.
+const NONTERMINAL_BASE = 0x80
+
+const debug = true
+
+type DebugLevel int
+
+const debugLevel = INFO
+const (
+	DEBUG DebugLevel = iota
+	INFO
+	WARNNING
+	ERROR
+)
+
+func unique(uint8Slice []uint8) []uint8 {
+	keys := make(map[uint8]bool)
+	list := []uint8{}
+	for _, entry := range uint8Slice {
+		if _, value := keys[entry]; !value {
+			keys[entry] = true
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+func debugPrintf(level DebugLevel, format string, a ...interface{}) {
+	if level >= debugLevel {
+		fmt.Printf(format, a...)
+	}
+}
+func debugPrint(level DebugLevel, format string) {
+	if level >= debugLevel {
+		fmt.Print(format)
+	}
+}
+
+type Token []uint8
+type GrammarLL1 struct {
+	grammar       map[uint8]([]Token)
+	terminals     []uint8
+	nonTerminals  []uint8
+	unfoldGrammar [](map[uint8](Token))
+	first         map[uint8]([]uint8)
+	follow        map[uint8]([]uint8)
+	parseTable    map[uint8](map[uint8]([]uint8))
+	translation   map[uint8]([]Token)
+	scheme        sdt.Scheme
+	QTs           []ir.Quad
+	ready         bool
+	Optimize      bool
+	ShowPasses    bool
+	CfgFilename   string
+	Dataflow      bool
+	//Backend, when set, runs on the quads of each translated program
+	Backend func(quads []ir.Quad) error
+}
+
+// BuildGrammar reads the grammar file and builds its FIRST and FOLLOW sets
+// and the parse table.
+func (Grammar *GrammarLL1) BuildGrammar(grammar_filename string) {
+	Grammar.grammar = make(map[uint8]([]Token))
+	Grammar.translation = make(map[uint8]([]Token))
+	Grammar.readGrammarFromFile(grammar_filename)
+	Grammar.genTerminalAndNonterminal()
+	Grammar.genUnfoldGrammar()
+	Grammar.genFirst()
+	Grammar.genFollow()
+	Grammar.printFirstFollow()
+	Grammar.genParseTable()
+	Grammar.QTs = make([]ir.Quad, 0)
+	Grammar.ready = true
+}
+func (Grammar *GrammarLL1) printFirstFollow() {
+	level := INFO
+	debugPrintf(level, " %10s   %10s\n", "first", "follow")
+	for key, _ := range Grammar.grammar {
+		debugPrintf(level, "%s -> ", symbolName(key))
+		firstStr := ""
+		followStr := ""
+		for _, token := range Grammar.first[key] {
+			firstStr += fmt.Sprintf("%s ", symbolName(token))
+		}
+		for _, token := range Grammar.follow[key] {
+			followStr += fmt.Sprintf("%s ", symbolName(token))
+		}
+		debugPrintf(level, " %-10s  %-10s\n", firstStr, followStr)
+	}
+}
+
+func printGrammar(grammar map[uint8]([]Token)) {
+	level := INFO
+	for key, value := range grammar {
+		debugPrintf(level, "%s -> ", symbolName(key))
+		for _, token := range value {
+			debugPrintf(level, "%s | ", stringfyToken(token))
+		}
+		debugPrint(level, "\n")
+	}
+	debugPrint(level, "\n")
+}
+func printUnfoldGrammar(unfoldGrammar [](map[uint8](Token))) {
+	level := DEBUG
+	for _, value := range unfoldGrammar {
+		for key, token := range value {
+			debugPrintf(level, "%s -> %s", symbolName(key), stringfyToken(token))
+			debugPrint(level, "\n")
+		}
+	}
+	debugPrint(level, "\n")
+}
+func (Grammar *GrammarLL1) readGrammarFromFile(grammar_filename string) {
+	//declear a empty map from uint8 to slice uint8
+	//read file
+	b, err := ioutil.ReadFile(grammar_filename)
+	if err != nil {
+		log.Print(err)
+	}
+	//[]byte to string
+	s := string(b)
+	//remove space
+	s = strings.Replace(s, " ", "", -1)
+	s = strings.Replace(s, "\r", "", -1)
+	//split by line
+	lines := strings.Split(string(s), "\n")
+	for _, line := range lines {
+		//split by ->
+		tokens := strings.SplitN(line, "->", 2)
+		//check tokens length
+		if len(tokens) != 2 {
+			log.Print("Error: grammar file format error")
+			os.Exit(1)
+		}
+		tokens[0] = sdt.CastSymbols(tokens[0], CHARCAST)
+		tokens[1] = sdt.CastSymbols(tokens[1], CHARCAST)
+		//split by | outside of action symbols
+		split_tokens := sdt.SplitAlternatives(tokens[1])
+		if len(tokens[0]) != 1 {
+			log.Print("Error: grammar file format error")
+			os.Exit(1)
+		}
+		//check if key in grammar
+		key := tokens[0][0]
+		if _, ok := Grammar.grammar[key]; !ok {
+			Grammar.grammar[key] = make([]Token, 0)
+			Grammar.translation[key] = make([]Token, 0)
+		}
+		for _, token := range split_tokens {
+			translation, production, err := Grammar.scheme.ReadAlternative(token)
+			if err != nil {
+				log.Printf("Error: %s", err)
+				os.Exit(1)
+			}
+			Grammar.grammar[key] = append(Grammar.grammar[key], Token(production))
+			Grammar.translation[key] = append(Grammar.translation[key], Token(translation))
+		}
+
+	}
+	printGrammar(Grammar.grammar)
+	Grammar.printTranslation()
+}
+func (Grammar *GrammarLL1) printTranslation() {
+	level := INFO
+	for key, value := range Grammar.translation {
+		debugPrintf(level, "%s -> ", symbolName(key))
+		for _, token := range value {
+			debugPrintf(level, "%s | ", Grammar.stringfySYN(token))
+		}
+		debugPrint(level, "\n")
+	}
+	debugPrint(level, "\n")
+}
+func printSlice(level DebugLevel, slice []uint8) {
+	for _, v := range slice {
+		debugPrintf(level, "%s ", symbolName(v))
+	}
+	debugPrint(level, "\n")
+}
+func (Grammar *GrammarLL1) genTerminalAndNonterminal() {
+	level := INFO
+	for key, value := range Grammar.grammar {
+		Grammar.nonTerminals = append(Grammar.nonTerminals, key)
+		for _, token := range value {
+			for _, char := range token {
+				if isTerminal(char) {
+					Grammar.terminals = append(Grammar.terminals, char)
+				}
+			}
+		}
+	}
+	Grammar.terminals = append(Grammar.terminals, '#')
+	Grammar.terminals = unique(Grammar.terminals)
+	Grammar.nonTerminals = unique(Grammar.nonTerminals)
+	debugPrint(level, "print terminal\n")
+	printSlice(level, Grammar.terminals)
+	debugPrint(level, "print nonTerminal\n")
+	printSlice(level, Grammar.nonTerminals)
+	debugPrint(level, "\n")
+}
+func (Grammar *GrammarLL1) genUnfoldGrammar() {
+	Grammar.unfoldGrammar = make([](map[uint8](Token)), 0)
+	for key, value := range Grammar.grammar {
+		for _, token := range value {
+			Grammar.unfoldGrammar = append(Grammar.unfoldGrammar, map[uint8](Token){key: token})
+		}
+	}
+	printUnfoldGrammar(Grammar.unfoldGrammar)
+
+}
+
+// type Node struct {
+// 	in   uint8
+// 	out  uint8
+// 	next []uint8
+// }
+// type Graph struct {
+// 	nodes map[uint8]Node
+// }
+
+// terminalOf returns the grammar terminal matching the type of a lexer token.
+func terminalOf(token lexer.Token) (uint8, bool) {
+	for terminal, tokenType := range TERMINALCAST {
+		if tokenType == token.Type {
+			return terminal, true
+		}
+	}
+	return 0, false
+}
+func isNonTerminal(token uint8) bool {
+	return (token >= 'A' && token <= 'Z') || (token >= NONTERMINAL_BASE && token < sdt.ACTION_BASE)
+}
+
+// symbolName is the name of a symbol as written in the grammar file, a non
+// terminal beyond 'A'-'Z' is written back as its primed name.
+func symbolName(symbol uint8) string {
+	if symbol >= NONTERMINAL_BASE {
+		for name, v := range CHARCAST {
+			if v == symbol {
+				return name
+			}
+		}
+	}
+	return string(symbol)
+}
+func stringfyToken(token Token) string {
+	str := ""
+	for _, v := range token {
+		str += symbolName(v)
+	}
+	return str
+}
+func isEmptyToken(token uint8) bool {
+	return token == 'e'
+}
+func isTerminal(token uint8) bool {
+	return !(isNonTerminal(token) || isEmptyToken(token) || sdt.IsAction(token))
+}
+
+func contains(slice []uint8, v uint8) bool {
+	for _, entry := range slice {
+		if entry == v {
+			return true
+		}
+	}
+	return false
+}
+
+// firstOf returns FIRST of a sequence of symbols, it holds e when the whole
+// sequence derives e. Action symbols derive e.
+func (Grammar *GrammarLL1) firstOf(token Token) []uint8 {
+	first := make([]uint8, 0)
+	for _, symbol := range token {
+		if isEmptyToken(symbol) || sdt.IsAction(symbol) {
+			continue
+		}
+		if isTerminal(symbol) {
+			return append(first, symbol)
+		}
+		for _, v := range Grammar.first[symbol] {
+			if !isEmptyToken(v) && !contains(first, v) {
+				first = append(first, v)
+			}
+		}
+		if !contains(Grammar.first[symbol], 'e') {
+			return first
+		}
+	}
+	return append(first, 'e')
+}
+
+// addSet adds the symbols to the set of key, telling whether it grew.
+func addSet(sets map[uint8]([]uint8), key uint8, symbols []uint8) bool {
+	changed := false
+	for _, v := range symbols {
+		if !contains(sets[key], v) {
+			sets[key] = append(sets[key], v)
+			changed = true
+		}
+	}
+	return changed
+}
+func printFirst(first map[uint8][]uint8) {
+	level := INFO
+	debugPrintf(level, "first list\n")
+	for key, value := range first {
+		debugPrintf(level, "%s -> ", symbolName(key))
+		for _, token := range value {
+			debugPrintf(level, "%s ", symbolName(token))
+		}
+		debugPrint(level, "\n")
+	}
+}
+
+// genFirst computes the FIRST sets, iterating until none of them grows.
+func (Grammar *GrammarLL1) genFirst() {
+	Grammar.first = make(map[uint8]([]uint8))
+	for changed := true; changed; {
+		changed = false
+		for key, value := range Grammar.grammar {
+			for _, token := range value {
+				if addSet(Grammar.first, key, Grammar.firstOf(token)) {
+					changed = true
+				}
+			}
+		}
+	}
+	printFirst(Grammar.first)
+}
+func printFollow(follow map[uint8][]uint8) {
+	level := INFO
+	debugPrint(level, "\nfollow list\n")
+	for key, value := range follow {
+		debugPrintf(level, "%s -> ", symbolName(key))
+		for _, token := range value {
+			debugPrintf(level, "%s ", symbolName(token))
+		}
+		debugPrint(level, "\n")
+	}
+	debugPrint(level, "\n")
+}
+
+// genFollow computes the FOLLOW sets, iterating until none of them grows.
+// What may follow a non terminal is FIRST of the rest of the production, and
+// FOLLOW of the left side when the rest derives e.
+func (Grammar *GrammarLL1) genFollow() {
+	level := DEBUG
+	Grammar.follow = make(map[uint8]([]uint8))
+	Grammar.follow['S'] = []uint8{'#'}
+	for changed := true; changed; {
+		changed = false
+		for key, value := range Grammar.grammar {
+			for _, token := range value {
+				for i, symbol := range token {
+					if !isNonTerminal(symbol) {
+						continue
+					}
+					rest := Grammar.firstOf(token[i+1:])
+					for _, v := range rest {
+						if isEmptyToken(v) {
+							if addSet(Grammar.follow, symbol, Grammar.follow[key]) {
+								changed = true
+							}
+						} else if addSet(Grammar.follow, symbol, []uint8{v}) {
+							changed = true
+						}
+					}
+				}
+			}
+		}
+		debugPrintf(level, "follow round, changed %v\n", changed)
+	}
+	printFollow(Grammar.follow)
+}
+func (Grammar *GrammarLL1) printParseTable() {
+	level := INFO
+	debugPrint(level, "\n      ParseTable\n")
+	title := "      "
+	for _, t := range Grammar.terminals {
+		title += fmt.Sprintf("%-6c", t)
+	}
+	debugPrintf(level, "%s\n", title)
+	for _, nt := range Grammar.nonTerminals {
+		debugPrintf(level, "%-6s", symbolName(nt))
+		printStr := ""
+		for _, t := range Grammar.terminals {
+			printStr += fmt.Sprintf("%-6s", Grammar.stringfySYN(Grammar.parseTable[nt][t]))
+		}
+		debugPrintf(level, "%s\n", printStr)
+	}
+}
+
+func (Grammar *GrammarLL1) genParseTable() {
+	level := INFO
+	debugPrint(level, "\n      genParseTable\n")
+	Grammar.parseTable = make(map[uint8](map[uint8][]uint8))
+	for _, key := range Grammar.nonTerminals {
+		Grammar.parseTable[key] = make(map[uint8][]uint8)
+	}
+	for key, value := range Grammar.grammar {
+		for i, token := range value {
+			for _, first := range Grammar.firstOf(token) {
+				if !isEmptyToken(first) {
+					Grammar.setParseTable(key, first, token, Grammar.translation[key][i])
+					continue
+				}
+				for _, follow := range Grammar.follow[key] {
+					Grammar.setParseTable(key, follow, token, Grammar.translation[key][i])
+				}
+			}
+		}
+	}
+	Grammar.printParseTable()
+}
+
+// setParseTable sets an entry of the parse table. On a conflict the empty
+// production gives way, so an else belongs to the nearest if, else the
+// production read first is kept.
+func (Grammar *GrammarLL1) setParseTable(key uint8, terminal uint8, token Token, translation Token) {
+	old, ok := Grammar.parseTable[key][terminal]
+	if !ok {
+		Grammar.parseTable[key][terminal] = translation
+		return
+	}
+	debugPrintf(WARNNING, "LL(1) conflict at [%s, %c]: %s / %s\n", symbolName(key), terminal, Grammar.stringfySYN(old), Grammar.stringfySYN(translation))
+	if Grammar.firstOf(Token(old))[0] == 'e' && token[0] != 'e' {
+		Grammar.parseTable[key][terminal] = translation
+	}
+}
+
+func (Grammar *GrammarLL1) stringfySYN(SYN []uint8) string {
+	str := ""
+	for _, v := range SYN {
+		if sdt.IsAction(v) {
+			str += "{" + Grammar.scheme.Action(v).String() + "}"
+		} else {
+			str += symbolName(v)
+		}
+	}
+	return str
+}
+func stringfySEM(SEM []sdt.Value) string {
+	ret := ""
+	for _, v := range SEM {
+		ret += v.String() + " "
+	}
+	return ret
+}
+func stringfyTokens(tokens []lexer.Token) string {
+	str := ""
+	for _, token := range tokens {
+		str += token.Literal
+	}
+	return str
+}
+func (Grammar *GrammarLL1) printState(stack []uint8, finishStack []uint8, SEM_stack []sdt.Value, QT ir.Quad, tokens []lexer.Token, index int) {
+	level := INFO
+	leftExppression := stringfyTokens(tokens[:index])
+	rightExppression := stringfyTokens(tokens[index:])
+	debugPrintf(level, "%-10s%5s%-20s%5s%-10s%5s%-10s\n", "matched", "", "matching", "", "SEM_stack", "", "QT")
+	debugPrintf(level, "%10s%5s%-20s%5s%-10s%5s%-10s\n", leftExppression, "", rightExppression, "", stringfySEM(SEM_stack), "", stringfyQT(QT))
+	copystack := make([]uint8, len(stack))
+	copy(copystack, stack)
+	//reverse stack
+	for i, j := 0, len(copystack)-1; i < j; i, j = i+1, j-1 {
+		copystack[i], copystack[j] = copystack[j], copystack[i]
+	}
+
+	debugPrintf(level, "%10s%5s%-10s\n\n", finishStack, "", Grammar.stringfySYN(copystack))
+}
+func (Grammar *GrammarLL1) printErrorState(stack []uint8, finishStack []uint8, tokens []lexer.Token, index int) {
+	level := ERROR
+	leftExppression := stringfyTokens(tokens[:index])
+	rightExppression := stringfyTokens(tokens[index:])
+	debugPrintf(level, "\nError state dump\n")
+	debugPrintf(level, "%-10s%5s%-10s\n", "matched", "", "matching")
+	debugPrintf(level, "%10s%5s%-10s\n", leftExppression, "", rightExppression)
+	copystack := make([]uint8, len(stack))
+	copy(copystack, stack)
+	//reverse stack
+	for i, j := 0, len(copystack)-1; i < j; i, j = i+1, j-1 {
+		copystack[i], copystack[j] = copystack[j], copystack[i]
+	}
+	debugPrintf(level, "%10s%5s%-10s\n", finishStack, "", Grammar.stringfySYN(copystack))
+}
+func stringfyQT(QT ir.Quad) string {
+	if QT.Op == "" {
+		return "none"
+	}
+	return QT.String()
+}
+func printQT(QT ir.Quad) {
+	level := INFO
+	if QT.IsLabel() {
+		debugPrintf(level, "%s:\n", QT.Result)
+		return
+	}
+	debugPrintf(level, "QT: %s\n", QT)
+}
+func (Grammar *GrammarLL1) PrintQuaternary() {
+	level := INFO
+	debugPrintf(level, "Quaternary:\n")
+	for _, v := range Grammar.QTs {
+		printQT(v)
+	}
+}
+
+// optimizeQuads runs the optimization passes over the quads when optimize
+// is set, with showPasses the quads before and after each pass are printed.
+func (Grammar *GrammarLL1) optimizeQuads() {
+	if !Grammar.Optimize {
+		return
+	}
+	//the copies propagated by the local pass may leave more to fold, and
+	//fold may rewrite quads without removing any, the passes run again
+	//until a round leaves the quads unchanged
+	for round := 1; ; round++ {
+		before := Grammar.QTs
+		quads, warnings := opt.Fold(Grammar.QTs)
+		if round == 1 {
+			for _, warning := range warnings {
+				debugPrintf(WARNNING, "Warning: %s\n", warning)
+			}
+		}
+		Grammar.showPass("fold", quads)
+		debugPrintf(INFO, "round %d: fold removed %d quads\n", round, len(Grammar.QTs)-len(quads))
+		Grammar.QTs = quads
+		quads, report := opt.Local(Grammar.QTs)
+		Grammar.showPass("local", quads)
+		debugPrintf(INFO, "round %d: %s\n", round, report)
+		Grammar.QTs = quads
+		if ir.Equal(before, Grammar.QTs) {
+			break
+		}
+	}
+}
+
+// showPass prints the quads before and after a pass when showPasses is set.
+func (Grammar *GrammarLL1) showPass(name string, after []ir.Quad) {
+	if !Grammar.ShowPasses {
+		return
+	}
+	fmt.Printf("Before %s:\n", name)
+	ir.Print(os.Stdout, Grammar.QTs)
+	fmt.Printf("After %s:\n", name)
+	ir.Print(os.Stdout, after)
+}
+
+// analyzeQuads builds the CFGs of the quads, writes them in DOT to
+// cfgFilename when it is set, "-" being the standard output, and with
+// dataflow prints the facts of the analyses at each block.
+func (Grammar *GrammarLL1) analyzeQuads() error {
+	if Grammar.CfgFilename == "" && !Grammar.Dataflow {
+		return nil
+	}
+	cfgs, err := opt.BuildCFGs(Grammar.QTs)
+	if err != nil {
+		return err
+	}
+	if Grammar.CfgFilename == "-" {
+		if err := opt.ExportDot(os.Stdout, cfgs); err != nil {
+			return err
+		}
+	} else if Grammar.CfgFilename != "" {
+		f, err := os.Create(Grammar.CfgFilename)
+		if err != nil {
+			return err
+		}
+		err = opt.ExportDot(f, cfgs)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if !Grammar.Dataflow {
+		return nil
+	}
+	for _, cfg := range cfgs {
+		name := cfg.Name
+		if name == "" {
+			name = "toplevel"
+		}
+		fmt.Printf("CFG %s\n", name)
+		reaching := opt.Solve(cfg, opt.NewReachingDefinitions(cfg))
+		live := opt.Solve(cfg, opt.NewLiveVariables(cfg))
+		available := opt.Solve(cfg, opt.NewAvailableExpressions(cfg))
+		for _, block := range cfg.Blocks {
+			fmt.Printf("  %s\n", cfg.BlockName(block))
+			fmt.Printf("    reaching  in %v out %v\n", reaching.In[block], reaching.Out[block])
+			fmt.Printf("    live      in %v out %v\n", live.In[block], live.Out[block])
+			fmt.Printf("    available in %v out %v\n", available.In[block], available.Out[block])
+		}
+	}
+	return nil
+}
+
+// ParseExpression lexes a program with the ex1 lexer and translates the
+// resulting token stream.
+func (Grammar *GrammarLL1) ParseExpression(expression string) error {
+	level := INFO
+	debugPrintf(level, "\nParseExpression  %s\n", expression)
+	return Grammar.ParseTokens(lexer.Lex([]byte(expression)))
+}
+
+// ParseTokens runs the LL(1) driver over a token stream, running the action
+// symbols of the translation scheme as they are popped. The quads are left
+// in Grammar.QTs.
+func (Grammar *GrammarLL1) ParseTokens(tokens []lexer.Token) error {
+	level := INFO
+	if !Grammar.ready {
+		debugPrintf(level, "Grammar not builded.\n")
+		return errors.New("Grammar not builded.")
+	}
+	step := 0
+	//add end symbol
+	tokens = append(tokens, lexer.Token{Type: lexer.EOF, Literal: "#"})
+	stack := make([]uint8, 0)
+	finishStack := make([]uint8, 0)
+	stack = append(stack, 'S')
+	translator := sdt.NewTranslator()
+	QT := ir.Quad{}
+	Grammar.QTs = translator.Quads()
+	for index := 0; len(stack) > 0; {
+		Grammar.printState(stack, finishStack, translator.SEM, QT, tokens, index)
+		QT = ir.Quad{}
+		token := tokens[index]
+		char, ok := terminalOf(token)
+		if !ok {
+			debugPrintf(ERROR, "Error: unexpected %s", token)
+			Grammar.printErrorState(stack, finishStack, tokens, index)
+			return errors.New("Error: unexpected " + token.Literal)
+		}
+		topStack := stack[len(stack)-1]
+		if sdt.IsAction(topStack) {
+			//run the semantic action
+			stack = stack[:len(stack)-1]
+			emitted := len(translator.Quads())
+			if err := Grammar.scheme.Run(topStack, translator); err != nil {
+				debugPrintf(ERROR, "Error: %s\n", err)
+				Grammar.printErrorState(stack, finishStack, tokens, index)
+				return err
+			}
+			if len(translator.Quads()) > emitted {
+				QT = translator.Quads()[len(translator.Quads())-1]
+			}
+		} else if isTerminal(topStack) {
+			if topStack == char {
+				debugPrintf(level, "step:%d match %c %s\n", step, topStack, token.Literal)
+				step++
+				translator.Last = token
+				finishStack = append(finishStack, topStack)
+				//pop stack
+				stack = stack[:len(stack)-1]
+				index++
+			} else {
+				debugPrintf(ERROR, "Error: %c != %s\n", topStack, token.Literal)
+				Grammar.printErrorState(stack, finishStack, tokens, index)
+				return errors.New("Error: " + string(topStack) + " != " + token.Literal)
+			}
+		} else if isNonTerminal(topStack) {
+			//lookup in ParseTable
+			production := Grammar.parseTable[topStack][char]
+			if len(production) == 0 {
+				Grammar.printErrorState(stack, finishStack, tokens, index)
+				return errors.New("Error: NonTerminal [" + symbolName(topStack) + "] lookup fail at " + token.Literal)
+			}
+			//pop stack
+			stack = stack[:len(stack)-1]
+			//push production, actions included
+			for i := len(production) - 1; i >= 0; i-- {
+				stack = append(stack, production[i])
+			}
+		} else if topStack == 'e' {
+			stack = stack[:len(stack)-1]
+		} else {
+			debugPrintf(ERROR, "Error: %c\n", topStack)
+			Grammar.printErrorState(stack, finishStack, tokens, index)
+			return errors.New("Error: " + string(topStack) + " != " + token.Literal)
+		}
+	}
+	Grammar.QTs = translator.Quads()
+	Grammar.optimizeQuads()
+	Grammar.PrintQuaternary()
+	if err := Grammar.analyzeQuads(); err != nil {
+		return err
+	}
+	if Grammar.Backend != nil {
+		return Grammar.Backend(Grammar.QTs)
+	}
+	return nil
+}
+
+// ParseAndPrint translates a program and prints whether it succeeded.
+func (Grammar *GrammarLL1) ParseAndPrint(expression string) {
+	err := Grammar.ParseExpression(expression)
+	if err != nil {
+		debugPrintf(ERROR, "Parse fail %s\n", err)
+	} else {
+		debugPrintf(ERROR, "Parse expression %s success.\n", expression)
+	}
+}
+
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"ex3/ll1"
+)
+
+var samplePrograms = []string{
+	"x = a+b*c;",
+	"x = a+b*(c+d)/f;",
+	"x = a < b && (c == 1 || !d);",
+	"x = (a >= b) + 1;",
+	"if (a < b) { m = b; } else m = a;",
+	"s = 0; i = 0; while (i < 10) { i = i+1; if (i == 5) continue; if (s > 20) break; s = s+i; }",
+	"do { n = n-1; } while (n > 0);",
+	"for (i = 0; i < n; i = i+1) { if (i == 3) break; s = s+i; }",
+	"s = 0; a = 2; t = a; a = a*10; a = s+t;",
+	"x = -a % b << 2 | ~c & d ^ !e; x += 1; x <<= y;",
+	"x = a*b + a*b; y = x; z = y*2 + a*b;",
+	"int max(int a, int b) { if (a > b) return a; return b; } int main() { int m = max(3, 7); printf(\"%d\\n\", m); return 0; }",
+}
+
+func main() {
+	grammar_filename := flag.String("grammar", "grammar.txt", "grammar file")
+	source_filename := flag.String("file", "", "translate this source file instead of the samples")
+	optimize := flag.Bool("O", false, "optimize the quads")
+	show_passes := flag.Bool("show-passes", false, "print the quads before and after each optimization pass")
+	cfg_filename := flag.String("cfg", "", "write the control flow graphs in DOT to this file, - for the standard output")
+	dataflow := flag.Bool("dataflow", false, "print reaching definitions, live variables and available expressions at each block")
+	flag.Parse()
+	Grammar := ll1.GrammarLL1{Optimize: *optimize, ShowPasses: *show_passes, CfgFilename: *cfg_filename, Dataflow: *dataflow}
+	//read grammar
+	Grammar.BuildGrammar(*grammar_filename)
+	if *source_filename != "" {
+		b, err := ioutil.ReadFile(*source_filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		Grammar.ParseAndPrint(string(b))
+		return
+	}
+	for _, program := range samplePrograms {
+		Grammar.ParseAndPrint(program)
+	}
+	//read from stdin
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Enter statements: ")
+		expression, readErr := reader.ReadString('\n')
+		if readErr != nil && expression == "" {
+			break
+		}
+		expression = strings.Replace(expression, "\r", "", -1)
+		expression = strings.Replace(expression, "\n", "", -1)
+		Grammar.ParseAndPrint(expression)
+	}
+
+}
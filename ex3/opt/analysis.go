@@ -0,0 +1,223 @@
+package opt
+
+import (
+	"fmt"
+
+	"ex3/ir"
+)
+
+// definedBy is the variable or temporary a quad assigns. A local declares
+// a variable without assigning it.
+func definedBy(quad ir.Quad) (ir.Operand, bool) {
+	if quad.Op == ir.LOCAL_OP || !(quad.Result.IsVar() || quad.Result.IsTemp()) {
+		return ir.Operand{}, false
+	}
+	return quad.Result, true
+}
+
+// globals are the variables the CFG uses that are not local to it.
+func (cfg *CFG) globals() Set {
+	globals := NewSet()
+	for _, block := range cfg.Blocks {
+		for _, quad := range block.Quads {
+			for _, v := range []ir.Operand{quad.Arg1, quad.Arg2, quad.Result} {
+				if v.IsVar() && !cfg.Locals[v] {
+					globals[v] = true
+				}
+			}
+		}
+	}
+	return globals
+}
+
+// Definition is a quad assigning a variable or a temporary, numbered in the
+// order of the quads of its CFG.
+type Definition struct {
+	ID   int
+	Name ir.Operand
+}
+
+func (definition Definition) String() string {
+	return fmt.Sprintf("d%d:%s", definition.ID, definition.Name)
+}
+
+// ReachingDefinitions is the forward problem of the definitions that may
+// reach a point without the variable they assign being assigned again. A
+// call may assign any global, so each call site defines every global
+// before its result.
+type ReachingDefinitions struct {
+	definitions map[*Block][]Definition
+}
+
+func NewReachingDefinitions(cfg *CFG) *ReachingDefinitions {
+	problem := &ReachingDefinitions{definitions: make(map[*Block][]Definition)}
+	globals := make(map[ir.Operand]bool)
+	for v := range cfg.globals() {
+		globals[v.(ir.Operand)] = true
+	}
+	sorted := sortedNames(globals)
+	id := 0
+	for _, block := range cfg.Blocks {
+		define := func(name ir.Operand) {
+			problem.definitions[block] = append(problem.definitions[block], Definition{ID: id, Name: name})
+			id++
+		}
+		for _, quad := range block.Quads {
+			if quad.Op == ir.CALL_OP {
+				for _, global := range sorted {
+					define(global)
+				}
+			}
+			if name, ok := definedBy(quad); ok {
+				define(name)
+			}
+		}
+	}
+	return problem
+}
+
+func (problem *ReachingDefinitions) Direction() Direction { return FORWARD }
+func (problem *ReachingDefinitions) Top() Fact            { return NewSet() }
+func (problem *ReachingDefinitions) Boundary() Fact       { return NewSet() }
+func (problem *ReachingDefinitions) Meet(a Fact, b Fact) Fact {
+	return a.(Set).Union(b.(Set))
+}
+func (problem *ReachingDefinitions) Equal(a Fact, b Fact) bool {
+	return a.(Set).Equal(b.(Set))
+}
+
+// Transfer kills the definitions of each name the block assigns and
+// generates the last one.
+func (problem *ReachingDefinitions) Transfer(block *Block, fact Fact) Fact {
+	reaching := fact.(Set).Copy()
+	for _, definition := range problem.definitions[block] {
+		for v := range reaching {
+			if v.(Definition).Name == definition.Name {
+				delete(reaching, v)
+			}
+		}
+		reaching[definition] = true
+	}
+	return reaching
+}
+
+// LiveVariables is the backward problem of the variables and temporaries
+// whose value may be read before they are assigned again. The globals are
+// live at the exit, where ex4 prints them or a caller may read them, and a
+// call may read any of them.
+type LiveVariables struct {
+	globals Set
+}
+
+func NewLiveVariables(cfg *CFG) *LiveVariables {
+	return &LiveVariables{globals: cfg.globals()}
+}
+
+func (problem *LiveVariables) Direction() Direction { return BACKWARD }
+func (problem *LiveVariables) Top() Fact            { return NewSet() }
+func (problem *LiveVariables) Boundary() Fact       { return problem.globals.Copy() }
+func (problem *LiveVariables) Meet(a Fact, b Fact) Fact {
+	return a.(Set).Union(b.(Set))
+}
+func (problem *LiveVariables) Equal(a Fact, b Fact) bool {
+	return a.(Set).Equal(b.(Set))
+}
+
+// Transfer goes through the quads backward, a name is dead above where it
+// is assigned and live above where it is read.
+func (problem *LiveVariables) Transfer(block *Block, fact Fact) Fact {
+	live := fact.(Set).Copy()
+	for i := len(block.Quads) - 1; i >= 0; i-- {
+		quad := block.Quads[i]
+		if name, ok := definedBy(quad); ok {
+			delete(live, name)
+		}
+		for _, v := range []ir.Operand{quad.Arg1, quad.Arg2} {
+			if v.IsVar() || v.IsTemp() {
+				live[v] = true
+			}
+		}
+		if quad.Op == ir.CALL_OP {
+			live = live.Union(problem.globals)
+		}
+	}
+	return live
+}
+
+// Expression is the computation of a quad, Arg2 is no operand for neg
+// and ~.
+type Expression struct {
+	Op   string
+	Arg1 ir.Operand
+	Arg2 ir.Operand
+}
+
+func (expression Expression) String() string {
+	if expression.Arg2.Kind == ir.NONE {
+		return expression.Op + " " + expression.Arg1.String()
+	}
+	return fmt.Sprintf("%s %s %s", expression.Arg1, expression.Op, expression.Arg2)
+}
+
+func (expression Expression) uses(name ir.Operand) bool {
+	return expression.Arg1 == name || expression.Arg2 == name
+}
+
+// expressionOf is the expression a quad computes, if it computes one.
+func expressionOf(quad ir.Quad) (Expression, bool) {
+	if quad.Op == "=" || !isPure(quad) {
+		return Expression{}, false
+	}
+	return Expression{Op: quad.Op, Arg1: quad.Arg1, Arg2: quad.Arg2}, true
+}
+
+// AvailableExpressions is the forward problem of the expressions computed
+// on every path to a point with none of their operands assigned since. A
+// call may assign any global.
+type AvailableExpressions struct {
+	universe Set
+	locals   map[ir.Operand]bool
+}
+
+func NewAvailableExpressions(cfg *CFG) *AvailableExpressions {
+	problem := &AvailableExpressions{universe: NewSet(), locals: cfg.Locals}
+	for _, block := range cfg.Blocks {
+		for _, quad := range block.Quads {
+			if expression, ok := expressionOf(quad); ok {
+				problem.universe[expression] = true
+			}
+		}
+	}
+	return problem
+}
+
+func (problem *AvailableExpressions) Direction() Direction { return FORWARD }
+func (problem *AvailableExpressions) Top() Fact            { return problem.universe.Copy() }
+func (problem *AvailableExpressions) Boundary() Fact       { return NewSet() }
+func (problem *AvailableExpressions) Meet(a Fact, b Fact) Fact {
+	return a.(Set).Intersect(b.(Set))
+}
+func (problem *AvailableExpressions) Equal(a Fact, b Fact) bool {
+	return a.(Set).Equal(b.(Set))
+}
+
+// Transfer generates the expression of each quad, then kills those reading
+// the name it assigns.
+func (problem *AvailableExpressions) Transfer(block *Block, fact Fact) Fact {
+	available := fact.(Set).Copy()
+	for _, quad := range block.Quads {
+		if expression, ok := expressionOf(quad); ok {
+			available[expression] = true
+		}
+		name, ok := definedBy(quad)
+		for v := range available {
+			expression := v.(Expression)
+			if ok && expression.uses(name) {
+				delete(available, v)
+			} else if quad.Op == ir.CALL_OP && (expression.Arg1.IsVar() && !problem.locals[expression.Arg1] || expression.Arg2.IsVar() && !problem.locals[expression.Arg2]) {
+				delete(available, v)
+			}
+		}
+	}
+	return available
+}
@@ -0,0 +1,103 @@
+package opt
+
+import (
+	"testing"
+
+	"ex3/ir"
+)
+
+func quad(op string, arg1 ir.Operand, arg2 ir.Operand, result ir.Operand) ir.Quad {
+	return ir.Quad{Op: op, Arg1: arg1, Arg2: arg2, Result: result}
+}
+
+var none = ir.Operand{}
+
+// LOOP is i = 0; m = n*2; while (i < n) i = i+1; x = i*2; out of the
+// functions, so every variable is global. Its blocks are B0 up to the loop,
+// B1 the test, B2 the body and B3 after the loop.
+var LOOP = []ir.Quad{
+	quad("=", ir.Const(0), none, ir.Var("i")),
+	quad("*", ir.Var("n"), ir.Const(2), ir.Var("m")),
+	quad(ir.LABEL_OP, none, none, ir.Label(0)),
+	quad("j>=", ir.Var("i"), ir.Var("n"), ir.Label(1)),
+	quad("+", ir.Var("i"), ir.Const(1), ir.Temp(0)),
+	quad("=", ir.Temp(0), none, ir.Var("i")),
+	quad("j", none, none, ir.Label(0)),
+	quad(ir.LABEL_OP, none, none, ir.Label(1)),
+	quad("*", ir.Var("i"), ir.Const(2), ir.Var("x")),
+}
+
+// CALL is a function main with the locals x and y calling f between
+// computing g+x and x*2 and using them, g is global. B0 ends at the return,
+// B1 is the endfunc.
+var CALL = []ir.Quad{
+	quad(ir.FUNC_OP, none, none, ir.Func("main")),
+	quad(ir.LOCAL_OP, none, none, ir.Var("x")),
+	quad(ir.LOCAL_OP, none, none, ir.Var("y")),
+	quad("=", ir.Const(1), none, ir.Var("g")),
+	quad("+", ir.Var("g"), ir.Var("x"), ir.Temp(0)),
+	quad("*", ir.Var("x"), ir.Const(2), ir.Temp(1)),
+	quad(ir.CALL_OP, ir.Func("f"), ir.Const(0), ir.Temp(2)),
+	quad("+", ir.Temp(0), ir.Temp(1), ir.Var("y")),
+	quad(ir.RETURN_OP, ir.Var("y"), none, none),
+	quad(ir.ENDFUNC_OP, none, none, ir.Func("main")),
+}
+
+// ANALYSES build the problem of each analysis on a CFG.
+var ANALYSES = map[string]func(cfg *CFG) Problem{
+	"reaching":  func(cfg *CFG) Problem { return NewReachingDefinitions(cfg) },
+	"live":      func(cfg *CFG) Problem { return NewLiveVariables(cfg) },
+	"available": func(cfg *CFG) Problem { return NewAvailableExpressions(cfg) },
+}
+
+func TestAnalyses(t *testing.T) {
+	tests := []struct {
+		program  string
+		quads    []ir.Quad
+		cfg      int
+		analysis string
+		block    int
+		in       string
+		out      string
+	}{
+		{"loop", LOOP, 0, "reaching", 0, "{}", "{d0:i, d1:m}"},
+		{"loop", LOOP, 0, "reaching", 1, "{d0:i, d1:m, d2:t0, d3:i}", "{d0:i, d1:m, d2:t0, d3:i}"},
+		{"loop", LOOP, 0, "reaching", 2, "{d0:i, d1:m, d2:t0, d3:i}", "{d1:m, d2:t0, d3:i}"},
+		{"loop", LOOP, 0, "reaching", 3, "{d0:i, d1:m, d2:t0, d3:i}", "{d0:i, d1:m, d2:t0, d3:i, d4:x}"},
+		{"loop", LOOP, 0, "live", 0, "{n}", "{i, m, n}"},
+		{"loop", LOOP, 0, "live", 1, "{i, m, n}", "{i, m, n}"},
+		{"loop", LOOP, 0, "live", 2, "{i, m, n}", "{i, m, n}"},
+		{"loop", LOOP, 0, "live", 3, "{i, m, n}", "{i, m, n, x}"},
+		{"loop", LOOP, 0, "available", 0, "{}", "{n * 2}"},
+		{"loop", LOOP, 0, "available", 1, "{n * 2}", "{n * 2}"},
+		{"loop", LOOP, 0, "available", 2, "{n * 2}", "{n * 2}"},
+		{"loop", LOOP, 0, "available", 3, "{n * 2}", "{i * 2, n * 2}"},
+		//the call defines g as d3, killing d0
+		{"call", CALL, 1, "reaching", 0, "{}", "{d1:t0, d2:t1, d3:g, d4:t2, d5:y}"},
+		//the call may read g, which is live at the exit
+		{"call", CALL, 1, "live", 0, "{x}", "{g}"},
+		//the call may assign g, so g+x is killed, x*2 is not
+		{"call", CALL, 1, "available", 0, "{}", "{t0 + t1, x * 2}"},
+	}
+	for _, test := range tests {
+		cfgs, err := BuildCFGs(test.quads)
+		if err != nil {
+			t.Fatalf("%s: %s", test.program, err)
+		}
+		cfg := cfgs[test.cfg]
+		solution := Solve(cfg, ANALYSES[test.analysis](cfg))
+		block := cfg.Blocks[test.block]
+		in, out := solution.In[block].(Set).String(), solution.Out[block].(Set).String()
+		if in != test.in || out != test.out {
+			t.Errorf("%s %s %s: in %s out %s, want in %s out %s",
+				test.program, test.analysis, cfg.BlockName(block), in, out, test.in, test.out)
+		}
+	}
+}
+
+func TestMissingLabel(t *testing.T) {
+	quads := []ir.Quad{quad("j", none, none, ir.Label(0))}
+	if _, err := BuildCFGs(quads); err == nil {
+		t.Errorf("a jump to a label not placed built a CFG")
+	}
+}
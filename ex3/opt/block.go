@@ -0,0 +1,53 @@
+package opt
+
+import "ex3/ir"
+
+// Block is a basic block: a run of quads entered only at the first and left
+// only after the last. Its predecessors and successors are set once it is
+// in a CFG.
+type Block struct {
+	ID    int
+	Quads []ir.Quad
+	Preds []*Block
+	Succs []*Block
+}
+
+// endsBlock tells whether the quad after this one leads a new block.
+func endsBlock(quad ir.Quad) bool {
+	return quad.IsJump() || quad.Op == ir.RETURN_OP || quad.Op == ir.ENDFUNC_OP
+}
+
+// Partition splits the quads into basic blocks. A block is led by the first
+// quad, a label, the beginning of a function and the quad after a jump, a
+// return or the end of a function.
+func Partition(quads []ir.Quad) []*Block {
+	blocks := make([]*Block, 0)
+	var block *Block
+	for i, quad := range quads {
+		//labels in a row lead the same block
+		if i == 0 || quad.Op == ir.FUNC_OP || endsBlock(quads[i-1]) || quad.IsLabel() && !onlyLabels(block.Quads) {
+			block = &Block{ID: len(blocks)}
+			blocks = append(blocks, block)
+		}
+		block.Quads = append(block.Quads, quad)
+	}
+	return blocks
+}
+
+func onlyLabels(quads []ir.Quad) bool {
+	for _, quad := range quads {
+		if !quad.IsLabel() {
+			return false
+		}
+	}
+	return true
+}
+
+// Join concatenates the quads of the blocks.
+func Join(blocks []*Block) []ir.Quad {
+	quads := make([]ir.Quad, 0)
+	for _, block := range blocks {
+		quads = append(quads, block.Quads...)
+	}
+	return quads
+}
@@ -0,0 +1,166 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"ex3/ir"
+)
+
+// CFG is the control flow graph of a procedure, a function or the code out
+// of the functions. Entry and Exit are empty blocks before the first block
+// and after every block that returns or ends the procedure. Locals are the
+// parameters and local variables of a function, any other variable is
+// global.
+type CFG struct {
+	Name   string
+	Blocks []*Block
+	Entry  *Block
+	Exit   *Block
+	Locals map[ir.Operand]bool
+}
+
+func (block *Block) addEdge(succ *Block) {
+	block.Succs = append(block.Succs, succ)
+	succ.Preds = append(succ.Preds, block)
+}
+
+// BlockName is B0, B1, ... or entry and exit.
+func (cfg *CFG) BlockName(block *Block) string {
+	switch block {
+	case cfg.Entry:
+		return "entry"
+	case cfg.Exit:
+		return "exit"
+	}
+	return fmt.Sprintf("B%d", block.ID)
+}
+
+// NewCFG partitions the quads of a procedure into blocks and links each to
+// the target of its last quad when it jumps and to the next block unless it
+// jumps always, returns or ends the function. A jump to a label the
+// procedure does not place is an error.
+func NewCFG(name string, quads []ir.Quad) (*CFG, error) {
+	blocks := Partition(quads)
+	cfg := &CFG{
+		Name:   name,
+		Blocks: blocks,
+		Entry:  &Block{ID: len(blocks)},
+		Exit:   &Block{ID: len(blocks) + 1},
+		Locals: make(map[ir.Operand]bool),
+	}
+	labels := make(map[ir.Operand]*Block)
+	for _, block := range blocks {
+		for _, quad := range block.Quads {
+			if quad.IsLabel() {
+				labels[quad.Result] = block
+			}
+			if quad.Op == ir.FORMAL_OP || quad.Op == ir.LOCAL_OP {
+				cfg.Locals[quad.Result] = true
+			}
+		}
+	}
+	if len(blocks) == 0 {
+		cfg.Entry.addEdge(cfg.Exit)
+		return cfg, nil
+	}
+	cfg.Entry.addEdge(blocks[0])
+	for i, block := range blocks {
+		last := block.Quads[len(block.Quads)-1]
+		if last.IsJump() {
+			target, ok := labels[last.Result]
+			if !ok {
+				return nil, fmt.Errorf("%s jumps to a label that is not placed", last)
+			}
+			block.addEdge(target)
+		}
+		switch {
+		case last.Op == "j":
+			//never falls through
+		case last.Op == ir.RETURN_OP || last.Op == ir.ENDFUNC_OP || i == len(blocks)-1:
+			block.addEdge(cfg.Exit)
+		default:
+			block.addEdge(blocks[i+1])
+		}
+	}
+	return cfg, nil
+}
+
+// BuildCFGs builds the CFG of the code out of the functions, named by an
+// empty string, and of each function.
+func BuildCFGs(quads []ir.Quad) ([]*CFG, error) {
+	toplevel := make([]ir.Quad, 0)
+	cfgs := make([]*CFG, 0)
+	var function []ir.Quad
+	for _, quad := range quads {
+		switch {
+		case quad.Op == ir.FUNC_OP:
+			function = []ir.Quad{quad}
+		case function != nil:
+			function = append(function, quad)
+			if quad.Op == ir.ENDFUNC_OP {
+				cfg, err := NewCFG(quad.Result.Name, function)
+				if err != nil {
+					return nil, err
+				}
+				cfgs = append(cfgs, cfg)
+				function = nil
+			}
+		default:
+			toplevel = append(toplevel, quad)
+		}
+	}
+	cfg, err := NewCFG("", toplevel)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*CFG{cfg}, cfgs...), nil
+}
+
+func dotEscape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, `"`, `\"`, -1)
+}
+
+// ExportDot writes the CFGs as a Graphviz digraph, a cluster per procedure,
+// each block labeled with its quads.
+func ExportDot(w io.Writer, cfgs []*CFG) error {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph CFG {\n")
+	buffer.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	for i, cfg := range cfgs {
+		name := cfg.Name
+		if name == "" {
+			name = "toplevel"
+		}
+		fmt.Fprintf(&buffer, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(&buffer, "\t\tlabel=\"%s\";\n", dotEscape(name))
+		node := func(block *Block) string {
+			return fmt.Sprintf("p%d_%s", i, cfg.BlockName(block))
+		}
+		fmt.Fprintf(&buffer, "\t\t%s [label=\"entry\", shape=ellipse];\n", node(cfg.Entry))
+		fmt.Fprintf(&buffer, "\t\t%s [label=\"exit\", shape=ellipse];\n", node(cfg.Exit))
+		for _, block := range cfg.Blocks {
+			label := cfg.BlockName(block) + "\\l"
+			for _, quad := range block.Quads {
+				if quad.IsLabel() {
+					label += dotEscape(quad.Result.String()+":") + "\\l"
+				} else {
+					label += dotEscape(quad.String()) + "\\l"
+				}
+			}
+			fmt.Fprintf(&buffer, "\t\t%s [label=\"%s\"];\n", node(block), label)
+		}
+		for _, block := range append([]*Block{cfg.Entry}, cfg.Blocks...) {
+			for _, succ := range block.Succs {
+				fmt.Fprintf(&buffer, "\t\t%s -> %s;\n", node(block), node(succ))
+			}
+		}
+		buffer.WriteString("\t}\n")
+	}
+	buffer.WriteString("}\n")
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
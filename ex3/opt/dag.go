@@ -0,0 +1,327 @@
+package opt
+
+import (
+	"fmt"
+	"sort"
+
+	"ex3/ir"
+)
+
+// Node is a node of the DAG of a block: a value, either the value an
+// operand has when the block is entered or that of op on its kids. names
+// are the variables and temporaries holding the value, holder is the one
+// the regenerated quads read it from. A temporary among names that is not
+// materialized was only a copy of the value and is never assigned.
+type Node struct {
+	id           int
+	op           string
+	kids         [2]*Node
+	holder       ir.Operand
+	names        map[ir.Operand]bool
+	materialized map[ir.Operand]bool
+}
+
+type nodeKey struct {
+	op   string
+	kids [2]*Node
+}
+
+// Report counts the quads each local optimization removed.
+type Report struct {
+	CSE       int
+	Copies    int
+	DeadTemps int
+}
+
+func (report Report) String() string {
+	return fmt.Sprintf("common subexpressions removed %d quads, copy propagation %d, dead temporaries %d",
+		report.CSE, report.Copies, report.DeadTemps)
+}
+
+// COMMUTATIVE are the operators whose operands can be swapped, so that a*b
+// and b*a are found to be the same node.
+var COMMUTATIVE = map[string]bool{"+": true, "*": true, "&": true, "|": true, "^": true}
+
+// isPure tells whether a quad only computes Result from its arguments, so
+// that it can be dropped or shared: a copy or an operator evaluate knows.
+func isPure(quad ir.Quad) bool {
+	if quad.Op == "=" {
+		return true
+	}
+	_, ok := evaluate(quad.Op, 1, 1)
+	return ok
+}
+
+// DAG is the DAG of the block being optimized and the quads regenerated
+// from it so far.
+type DAG struct {
+	nodes   []*Node
+	table   map[nodeKey]*Node
+	current map[ir.Operand]*Node
+	locals  map[ir.Operand]bool
+	escapes map[ir.Operand]bool
+	quads   []ir.Quad
+	report  *Report
+}
+
+func (dag *DAG) newNode(op string, kids [2]*Node) *Node {
+	node := &Node{id: len(dag.nodes), op: op, kids: kids, names: make(map[ir.Operand]bool), materialized: make(map[ir.Operand]bool)}
+	dag.nodes = append(dag.nodes, node)
+	return node
+}
+
+// nodeOf is the node of the current value of an operand, a leaf if it was
+// not assigned in the block. A constant is its own holder.
+func (dag *DAG) nodeOf(operand ir.Operand) *Node {
+	if node, ok := dag.current[operand]; ok {
+		return node
+	}
+	node := dag.newNode("", [2]*Node{})
+	node.holder = operand
+	if !operand.IsConst() {
+		dag.bind(operand, node, true)
+	} else {
+		dag.current[operand] = node
+	}
+	return node
+}
+
+func (dag *DAG) bind(name ir.Operand, node *Node, materialized bool) {
+	dag.current[name] = node
+	node.names[name] = true
+	if materialized {
+		node.materialized[name] = true
+		if node.holder.Kind == ir.NONE {
+			node.holder = name
+		}
+	}
+}
+
+// kill is called before name is assigned: it no longer holds the value of
+// its node. Were it the last holder of a value some temporary is a copy of,
+// that temporary is assigned the value first.
+func (dag *DAG) kill(name ir.Operand) {
+	node, ok := dag.current[name]
+	if !ok {
+		return
+	}
+	delete(dag.current, name)
+	delete(node.names, name)
+	delete(node.materialized, name)
+	if node.holder != name {
+		return
+	}
+	node.holder = ir.Operand{}
+	if holders := sortedNames(node.materialized); len(holders) > 0 {
+		node.holder = holders[0]
+	} else if copies := sortedNames(node.names); len(copies) > 0 {
+		dag.quads = append(dag.quads, ir.Quad{Op: "=", Arg1: name, Result: copies[0]})
+		node.materialized[copies[0]] = true
+		node.holder = copies[0]
+	} else {
+		//nothing holds the value anymore, it can not be shared
+		delete(dag.table, nodeKey{node.op, node.kids})
+	}
+}
+
+func sortedNames(names map[ir.Operand]bool) []ir.Operand {
+	sorted := make([]ir.Operand, 0, len(names))
+	for v := range names {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+	return sorted
+}
+
+// assign makes name hold the value of node by the quad, unless name holds
+// it already or is a temporary used only within the block and something
+// else holds it, then name is only bound to the node. It tells whether the
+// quad was emitted.
+func (dag *DAG) assign(name ir.Operand, node *Node, quad ir.Quad) bool {
+	if dag.current[name] == node && node.materialized[name] {
+		return false
+	}
+	dag.kill(name)
+	if name.IsTemp() && !dag.escapes[name] && node.holder.Kind != ir.NONE {
+		dag.bind(name, node, false)
+		return false
+	}
+	dag.quads = append(dag.quads, quad)
+	dag.bind(name, node, true)
+	return true
+}
+
+// coalesce turns a copy into name of a value just computed into a
+// temporary used only within the block into computing it into name, the
+// temporary is left a copy of it. It tells whether it did so.
+func (dag *DAG) coalesce(name ir.Operand, node *Node) bool {
+	last := len(dag.quads) - 1
+	temp := node.holder
+	if last < 0 || dag.quads[last].Result != temp || !temp.IsTemp() || dag.escapes[temp] || !isPure(dag.quads[last]) {
+		return false
+	}
+	if name == temp || name.IsTemp() && !dag.escapes[name] {
+		return false
+	}
+	quad := dag.quads[last]
+	dag.quads = dag.quads[:last]
+	//the copies of the value name had read it before it is assigned
+	dag.kill(name)
+	quad.Result = name
+	dag.quads = append(dag.quads, quad)
+	delete(node.materialized, temp)
+	dag.bind(name, node, true)
+	node.holder = name
+	return true
+}
+
+// killGlobals is called after a call, which may assign any variable that
+// is not local to the function.
+func (dag *DAG) killGlobals() {
+	for name := range dag.current {
+		if name.IsVar() && !dag.locals[name] {
+			dag.kill(name)
+		}
+	}
+}
+
+func (dag *DAG) operand(operand ir.Operand) ir.Operand {
+	if !operand.IsVar() && !operand.IsTemp() {
+		return operand
+	}
+	return dag.nodeOf(operand).holder
+}
+
+// optimizeBlock builds the DAG of a block and regenerates its quads in
+// their order: a value found again is read from a name that holds it, a
+// copy into a temporary only binds the temporary.
+func (dag *DAG) optimizeBlock(block *Block) []ir.Quad {
+	dag.nodes = make([]*Node, 0)
+	dag.table = make(map[nodeKey]*Node)
+	dag.current = make(map[ir.Operand]*Node)
+	dag.quads = make([]ir.Quad, 0, len(block.Quads))
+	for _, quad := range block.Quads {
+		switch quad.Op {
+		case ir.FUNC_OP, ir.ENDFUNC_OP:
+			dag.locals = make(map[ir.Operand]bool)
+		case ir.FORMAL_OP, ir.LOCAL_OP:
+			dag.locals[quad.Result] = true
+			dag.kill(quad.Result)
+		}
+		if !isPure(quad) {
+			quad.Arg1 = dag.operand(quad.Arg1)
+			quad.Arg2 = dag.operand(quad.Arg2)
+			if quad.Op == ir.CALL_OP {
+				dag.kill(quad.Result)
+				dag.quads = append(dag.quads, quad)
+				dag.killGlobals()
+				dag.bind(quad.Result, dag.newNode(ir.CALL_OP, [2]*Node{}), true)
+				continue
+			}
+			dag.quads = append(dag.quads, quad)
+			continue
+		}
+		if quad.Op == "=" {
+			node := dag.nodeOf(quad.Arg1)
+			if dag.coalesce(quad.Result, node) {
+				dag.report.Copies++
+			} else if !dag.assign(quad.Result, node, ir.Quad{Op: "=", Arg1: node.holder, Result: quad.Result}) {
+				dag.report.Copies++
+			}
+			continue
+		}
+		kids := [2]*Node{dag.nodeOf(quad.Arg1)}
+		if quad.Arg2.Kind != ir.NONE {
+			kids[1] = dag.nodeOf(quad.Arg2)
+		}
+		if COMMUTATIVE[quad.Op] && kids[0].id > kids[1].id {
+			kids[0], kids[1] = kids[1], kids[0]
+		}
+		key := nodeKey{quad.Op, kids}
+		if node, ok := dag.table[key]; ok {
+			//a variable is still assigned the value, read from its holder
+			if !dag.assign(quad.Result, node, ir.Quad{Op: "=", Arg1: node.holder, Result: quad.Result}) {
+				dag.report.CSE++
+			}
+			continue
+		}
+		node := dag.newNode(quad.Op, kids)
+		regenerated := ir.Quad{Op: quad.Op, Arg1: dag.operand(quad.Arg1), Arg2: dag.operand(quad.Arg2), Result: quad.Result}
+		//a new node has no holder, its quad is always emitted
+		dag.assign(quad.Result, node, regenerated)
+		dag.table[key] = node
+	}
+	return dag.quads
+}
+
+// escaping are the temporaries that are assigned more than once or used out
+// of the block assigning them, these are kept like variables.
+func escaping(blocks []*Block) map[ir.Operand]bool {
+	defs := make(map[ir.Operand]int)
+	block_of := make(map[ir.Operand]int)
+	escapes := make(map[ir.Operand]bool)
+	for _, block := range blocks {
+		for _, quad := range block.Quads {
+			if quad.Result.IsTemp() {
+				defs[quad.Result]++
+				block_of[quad.Result] = block.ID
+			}
+		}
+	}
+	for _, block := range blocks {
+		for _, quad := range block.Quads {
+			for _, v := range []ir.Operand{quad.Arg1, quad.Arg2} {
+				if v.IsTemp() && (defs[v] != 1 || block_of[v] != block.ID) {
+					escapes[v] = true
+				}
+			}
+		}
+	}
+	for v, n := range defs {
+		if n > 1 {
+			escapes[v] = true
+		}
+	}
+	return escapes
+}
+
+// RemoveDeadTemps drops the pure quads assigning a temporary nothing reads,
+// until there are none left. It returns the quads left and the number
+// dropped.
+func RemoveDeadTemps(quads []ir.Quad) ([]ir.Quad, int) {
+	removed := 0
+	for {
+		used := make(map[ir.Operand]bool)
+		for _, quad := range quads {
+			used[quad.Arg1] = true
+			used[quad.Arg2] = true
+		}
+		alive := make([]ir.Quad, 0, len(quads))
+		for _, quad := range quads {
+			if quad.Result.IsTemp() && !used[quad.Result] && isPure(quad) {
+				continue
+			}
+			alive = append(alive, quad)
+		}
+		if len(alive) == len(quads) {
+			return quads, removed
+		}
+		removed += len(quads) - len(alive)
+		quads = alive
+	}
+}
+
+// Local optimizes each basic block through its DAG: common subexpressions
+// are computed once, copies into temporaries are propagated and the
+// temporaries left unread are removed.
+func Local(quads []ir.Quad) ([]ir.Quad, Report) {
+	report := Report{}
+	blocks := Partition(quads)
+	dag := &DAG{locals: make(map[ir.Operand]bool), escapes: escaping(blocks), report: &report}
+	for _, block := range blocks {
+		block.Quads = dag.optimizeBlock(block)
+	}
+	optimized, dead := RemoveDeadTemps(Join(blocks))
+	report.DeadTemps = dead
+	return optimized, report
+}
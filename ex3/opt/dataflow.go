@@ -0,0 +1,145 @@
+package opt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Direction is the way facts flow in a data-flow problem.
+type Direction int
+
+const (
+	FORWARD Direction = iota
+	BACKWARD
+)
+
+// Fact is an element of the lattice of a data-flow problem.
+type Fact interface{}
+
+// Problem is a data-flow problem over a CFG: its direction, its lattice
+// given by Top, the initial fact of every block, and Meet, which combines
+// the facts flowing into a block, Boundary, the fact at the entry of a
+// forward problem and at the exit of a backward one, and Transfer, which
+// is the fact on the far side of a block from the one flowing into it.
+type Problem interface {
+	Direction() Direction
+	Top() Fact
+	Boundary() Fact
+	Meet(a Fact, b Fact) Fact
+	Equal(a Fact, b Fact) bool
+	Transfer(block *Block, fact Fact) Fact
+}
+
+// Solution are the facts at the beginning and at the end of every block.
+type Solution struct {
+	In  map[*Block]Fact
+	Out map[*Block]Fact
+}
+
+// Solve runs the iterative algorithm: every block starts at Top, then the
+// facts of each are recomputed from its neighbours, in the order of the
+// blocks for a forward problem and in reverse for a backward one, until
+// none changes.
+func Solve(cfg *CFG, problem Problem) *Solution {
+	solution := &Solution{In: make(map[*Block]Fact), Out: make(map[*Block]Fact)}
+	blocks := append([]*Block{cfg.Entry}, cfg.Blocks...)
+	blocks = append(blocks, cfg.Exit)
+	//for a backward problem the roles of In and Out, Preds and Succs swap
+	before, after := solution.In, solution.Out
+	neighbours := func(block *Block) []*Block { return block.Preds }
+	start := cfg.Entry
+	if problem.Direction() == BACKWARD {
+		before, after = after, before
+		neighbours = func(block *Block) []*Block { return block.Succs }
+		start = cfg.Exit
+		for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+			blocks[i], blocks[j] = blocks[j], blocks[i]
+		}
+	}
+	for _, block := range blocks {
+		before[block] = problem.Top()
+		after[block] = problem.Top()
+	}
+	before[start] = problem.Boundary()
+	after[start] = problem.Boundary()
+	for changed := true; changed; {
+		changed = false
+		for _, block := range blocks {
+			if block == start {
+				continue
+			}
+			fact := problem.Top()
+			for _, neighbour := range neighbours(block) {
+				fact = problem.Meet(fact, after[neighbour])
+			}
+			before[block] = fact
+			fact = problem.Transfer(block, fact)
+			if !problem.Equal(fact, after[block]) {
+				after[block] = fact
+				changed = true
+			}
+		}
+	}
+	return solution
+}
+
+// Set is the fact of the problems on sets: definitions, variables or
+// expressions.
+type Set map[interface{}]bool
+
+func NewSet(elements ...interface{}) Set {
+	set := make(Set)
+	for _, v := range elements {
+		set[v] = true
+	}
+	return set
+}
+
+func (set Set) Copy() Set {
+	copied := make(Set, len(set))
+	for v := range set {
+		copied[v] = true
+	}
+	return copied
+}
+
+func (set Set) Union(other Set) Set {
+	union := set.Copy()
+	for v := range other {
+		union[v] = true
+	}
+	return union
+}
+
+func (set Set) Intersect(other Set) Set {
+	intersection := make(Set)
+	for v := range set {
+		if other[v] {
+			intersection[v] = true
+		}
+	}
+	return intersection
+}
+
+func (set Set) Equal(other Set) bool {
+	if len(set) != len(other) {
+		return false
+	}
+	for v := range set {
+		if !other[v] {
+			return false
+		}
+	}
+	return true
+}
+
+// String writes the elements sorted, as {a, b, t0}.
+func (set Set) String() string {
+	elements := make([]string, 0, len(set))
+	for v := range set {
+		elements = append(elements, fmt.Sprint(v))
+	}
+	sort.Strings(elements)
+	return "{" + strings.Join(elements, ", ") + "}"
+}
@@ -0,0 +1,186 @@
+// Package opt holds the optimization passes over the quadruples of ex3/ir.
+package opt
+
+import (
+	"fmt"
+
+	"ex3/ir"
+)
+
+// evaluate computes a binary or unary operator on constants as the int of
+// the target does: 32 bits wrapping around, division truncated, the shift
+// count taken modulo 32 as x86 does. ok is false for an operator that is
+// not arithmetic and for a division by zero.
+func evaluate(op string, a int, b int) (value int, ok bool) {
+	x, y := int32(a), int32(b)
+	switch op {
+	case "+":
+		return int(x + y), true
+	case "-":
+		return int(x - y), true
+	case "*":
+		return int(x * y), true
+	case "/", "%":
+		if y == 0 {
+			return 0, false
+		}
+		if op == "/" {
+			return int(x / y), true
+		}
+		return int(x % y), true
+	case "<<":
+		return int(x << (uint32(y) & 31)), true
+	case ">>":
+		return int(x >> (uint32(y) & 31)), true
+	case "&":
+		return int(x & y), true
+	case "|":
+		return int(x | y), true
+	case "^":
+		return int(x ^ y), true
+	case "neg":
+		return int(-x), true
+	case "~":
+		return int(^x), true
+	}
+	return 0, false
+}
+
+// compare tells whether a relational jump on constants is taken.
+func compare(op string, x int, y int) (taken bool, ok bool) {
+	switch op {
+	case "j<":
+		return x < y, true
+	case "j>":
+		return x > y, true
+	case "j<=":
+		return x <= y, true
+	case "j>=":
+		return x >= y, true
+	case "j==":
+		return x == y, true
+	case "j!=":
+		return x != y, true
+	}
+	return false, false
+}
+
+// log2 is k when value is 2^k with k >= 1, else -1.
+func log2(value int) int {
+	k := 0
+	for value > 1 && value%2 == 0 {
+		value /= 2
+		k++
+	}
+	if value != 1 || k == 0 {
+		return -1
+	}
+	return k
+}
+
+// simplify applies the algebraic identities to a binary quad, x*2^k
+// becomes x << k and an identity leaves a copy =.
+func simplify(quad ir.Quad) ir.Quad {
+	copyOf := func(operand ir.Operand) ir.Quad {
+		return ir.Quad{Op: "=", Arg1: operand, Result: quad.Result}
+	}
+	a, b := quad.Arg1, quad.Arg2
+	isConst := func(operand ir.Operand, value int) bool {
+		return operand.IsConst() && operand.Value == value
+	}
+	switch quad.Op {
+	case "+", "|", "^":
+		if isConst(b, 0) {
+			return copyOf(a)
+		}
+		if isConst(a, 0) {
+			return copyOf(b)
+		}
+	case "-", "<<", ">>":
+		if isConst(b, 0) {
+			return copyOf(a)
+		}
+	case "*":
+		if isConst(a, 0) || isConst(b, 0) {
+			return copyOf(ir.Const(0))
+		}
+		if isConst(b, 1) {
+			return copyOf(a)
+		}
+		if isConst(a, 1) {
+			return copyOf(b)
+		}
+		if b.IsConst() && log2(b.Value) > 0 {
+			return ir.Quad{Op: "<<", Arg1: a, Arg2: ir.Const(log2(b.Value)), Result: quad.Result}
+		}
+		if a.IsConst() && log2(a.Value) > 0 {
+			return ir.Quad{Op: "<<", Arg1: b, Arg2: ir.Const(log2(a.Value)), Result: quad.Result}
+		}
+	case "/":
+		if isConst(b, 1) {
+			return copyOf(a)
+		}
+	case "%":
+		if isConst(b, 1) {
+			return copyOf(ir.Const(0))
+		}
+	case "&":
+		if isConst(a, 0) || isConst(b, 0) {
+			return copyOf(ir.Const(0))
+		}
+	}
+	return quad
+}
+
+// Fold folds the quads whose operands are constants and applies the
+// algebraic identities. A temporary assigned once a constant is replaced by
+// it where it is used, a jump on constants becomes j or is dropped. A
+// division by a constant zero is left to run, it is reported in the
+// returned warnings.
+func Fold(quads []ir.Quad) ([]ir.Quad, []error) {
+	defs := make(map[ir.Operand]int)
+	for _, quad := range quads {
+		if quad.Result.IsTemp() {
+			defs[quad.Result]++
+		}
+	}
+	constants := make(map[ir.Operand]ir.Operand)
+	substitute := func(operand ir.Operand) ir.Operand {
+		if value, ok := constants[operand]; ok {
+			return value
+		}
+		return operand
+	}
+	folded := make([]ir.Quad, 0, len(quads))
+	warnings := make([]error, 0)
+	for _, quad := range quads {
+		quad.Arg1 = substitute(quad.Arg1)
+		quad.Arg2 = substitute(quad.Arg2)
+		if (quad.Op == "/" || quad.Op == "%") && quad.Arg2.IsConst() && quad.Arg2.Value == 0 {
+			warnings = append(warnings, fmt.Errorf("division by zero in %s", quad))
+			folded = append(folded, quad)
+			continue
+		}
+		if quad.Arg1.IsConst() && (quad.Arg2.IsConst() || quad.Arg2.Kind == ir.NONE) {
+			if value, ok := evaluate(quad.Op, quad.Arg1.Value, quad.Arg2.Value); ok {
+				quad = ir.Quad{Op: "=", Arg1: ir.Const(value), Result: quad.Result}
+			} else if taken, ok := compare(quad.Op, quad.Arg1.Value, quad.Arg2.Value); ok || quad.Op == "jnz" {
+				if quad.Op == "jnz" {
+					taken = quad.Arg1.Value != 0
+				}
+				if taken {
+					folded = append(folded, ir.Quad{Op: "j", Result: quad.Result})
+				}
+				continue
+			}
+		} else if quad.Arg2.IsConst() || quad.Arg1.IsConst() {
+			quad = simplify(quad)
+		}
+		if quad.Op == "=" && quad.Arg1.IsConst() && quad.Result.IsTemp() && defs[quad.Result] == 1 {
+			constants[quad.Result] = quad.Arg1
+			continue
+		}
+		folded = append(folded, quad)
+	}
+	return folded, warnings
+}
@@ -0,0 +1,731 @@
+// Package sdt runs the syntax directed translation schemes of the LL(1)
+// drivers: it reads the action symbols written in the productions of a
+// grammar file and runs them on a Translator, which builds the quads.
+package sdt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ex3/ir"
+
+	"example.com/m/lexer"
+)
+
+// ACTION_BASE is the first byte given to the action symbols of a translation
+// scheme. An action {NAME(arg)} in the grammar file becomes the byte
+// ACTION_BASE+i, where i indexes Scheme.Actions.
+const ACTION_BASE = 0xA0
+
+// ActionSymbol is an action embedded in a production, the name of a
+// registered SemanticAction and its argument.
+type ActionSymbol struct {
+	Name string
+	Arg  string
+}
+
+func (action ActionSymbol) String() string {
+	if action.Arg == "" {
+		return action.Name
+	}
+	return action.Name + "(" + action.Arg + ")"
+}
+
+// Value is an entry of the semantic stack: an operand, or a boolean
+// expression as the lists of its jumps taken when it is true and when it is
+// false, the targets of which are backpatched once they are known, or the
+// arguments of a call read so far. No operand stands for an omitted
+// expression.
+type Value struct {
+	operand   ir.Operand
+	boolean   bool
+	truelist  []int
+	falselist []int
+	call      bool
+	args      []ir.Operand
+}
+
+func (value Value) String() string {
+	if value.boolean {
+		return fmt.Sprintf("B%v%v", value.truelist, value.falselist)
+	}
+	if value.call {
+		return fmt.Sprintf("args%v", value.args)
+	}
+	return value.operand.String()
+}
+
+func (value Value) isNone() bool {
+	return !value.boolean && !value.call && value.operand.Kind == ir.NONE
+}
+
+// Loop is an enclosing loop: the label it begins at, the label of the step
+// of a for loop, and the jumps into its body, out of it and to its next
+// iteration waiting for their target.
+type Loop struct {
+	begin     ir.Operand
+	step      ir.Operand
+	body      []int
+	breaks    []int
+	continues []int
+}
+
+// Translator is the state the semantic actions work on: the quads built so
+// far, the semantic stack, the enclosing loops, the function being defined
+// with the number of its parameters and the last matched token.
+type Translator struct {
+	builder  *ir.Builder
+	SEM      []Value
+	loops    []*Loop
+	function ir.Operand
+	formals  int
+	Last     lexer.Token
+}
+
+func NewTranslator() *Translator {
+	return &Translator{builder: ir.NewBuilder(), SEM: make([]Value, 0)}
+}
+
+// Quads are the quads built so far.
+func (translator *Translator) Quads() []ir.Quad {
+	return translator.builder.Quads
+}
+
+func (translator *Translator) push(value Value) {
+	translator.SEM = append(translator.SEM, value)
+}
+func (translator *Translator) pushOperand(operand ir.Operand) {
+	translator.push(Value{operand: operand})
+}
+func (translator *Translator) pop() (Value, error) {
+	if len(translator.SEM) == 0 {
+		return Value{}, errors.New("semantic stack is empty")
+	}
+	value := translator.SEM[len(translator.SEM)-1]
+	translator.SEM = translator.SEM[:len(translator.SEM)-1]
+	return value, nil
+}
+
+// popOperand pops a value as an operand, a boolean expression is evaluated
+// into a temporary holding 1 or 0.
+func (translator *Translator) popOperand() (ir.Operand, error) {
+	value, err := translator.pop()
+	if err != nil {
+		return ir.Operand{}, err
+	}
+	if !value.boolean {
+		return value.operand, nil
+	}
+	builder := translator.builder
+	temp := builder.NewTemp()
+	builder.Backpatch(value.truelist, builder.PlaceLabel())
+	builder.Emit("=", ir.Const(1), ir.Operand{}, temp)
+	end := builder.Emit("j", ir.Operand{}, ir.Operand{}, ir.Operand{})
+	builder.Backpatch(value.falselist, builder.PlaceLabel())
+	builder.Emit("=", ir.Const(0), ir.Operand{}, temp)
+	builder.Backpatch([]int{end}, builder.PlaceLabel())
+	return temp, nil
+}
+
+// popNone pops the top of the stack if it is an omitted expression.
+func (translator *Translator) popNone() bool {
+	if len(translator.SEM) == 0 || !translator.SEM[len(translator.SEM)-1].isNone() {
+		return false
+	}
+	translator.SEM = translator.SEM[:len(translator.SEM)-1]
+	return true
+}
+
+// popCondition pops a value as a boolean expression, an operand is true
+// when it is not zero.
+func (translator *Translator) popCondition() (Value, error) {
+	value, err := translator.pop()
+	if err != nil || value.boolean {
+		return value, err
+	}
+	builder := translator.builder
+	return Value{
+		boolean:   true,
+		truelist:  []int{builder.Emit("jnz", value.operand, ir.Operand{}, ir.Operand{})},
+		falselist: []int{builder.Emit("j", ir.Operand{}, ir.Operand{}, ir.Operand{})},
+	}, nil
+}
+
+func (translator *Translator) loop() (*Loop, error) {
+	if len(translator.loops) == 0 {
+		return nil, errors.New("not within a loop")
+	}
+	return translator.loops[len(translator.loops)-1], nil
+}
+
+// endLoop places the label after a loop, the target of its breaks.
+func (translator *Translator) endLoop(loop *Loop) {
+	translator.builder.Backpatch(loop.breaks, translator.builder.PlaceLabel())
+	translator.loops = translator.loops[:len(translator.loops)-1]
+}
+
+func (translator *Translator) jump(label ir.Operand) int {
+	return translator.builder.Emit("j", ir.Operand{}, ir.Operand{}, label)
+}
+
+// SemanticAction runs when its action symbol is popped from the parse stack.
+type SemanticAction func(translator *Translator, arg string) error
+
+// operandOf is the operand of a number, an identifier or a string token.
+func operandOf(token lexer.Token) (ir.Operand, error) {
+	if token.Type == lexer.IDENTIFIER {
+		return ir.Var(token.Literal), nil
+	}
+	if token.Type == lexer.STRING {
+		return ir.Str(token.Literal), nil
+	}
+	value, err := strconv.Atoi(token.Literal)
+	if err != nil {
+		return ir.Operand{}, fmt.Errorf("%s is not an integer", token.Literal)
+	}
+	return ir.Const(value), nil
+}
+
+// SEMANTIC_ACTIONS are the actions a translation scheme can use.
+// PUSH pushes the operand of the last matched token, GEQ(op) pops the right
+// and left operand and pushes the temporary holding left op right, UNARY(op)
+// does so with a single operand. ASSIGN pops a value and the variable it is
+// assigned to, COMPOUND(op) assigns the variable op the value to it. VAL
+// evaluates a boolean expression on top of the stack, it must run before the
+// code of a right operand is emitted.
+// REL(op) pops two operands and pushes the boolean expression left op right.
+// OR and AND run between their operands and patch the jumps of the left one
+// that go to the right one, ENDOR and ENDAND join both operands, NOT swaps
+// the lists of a boolean expression.
+// THEN runs after the condition of an if and leaves its false jumps on the
+// stack, ELSE jumps over the else branch and ENDIF places the label after
+// the statement. LOOP begins a loop, DO runs after the condition of a while
+// and ENDWHILE jumps back. A do while places the target of its continues
+// at DOCOND and jumps back at ENDDO. A for runs its step at FORSTEP, after
+// its condition, its body at FORBODY and jumps back to the step at ENDFOR,
+// an omitted condition is true. BREAK and CONTINUE jump out of and to the
+// next iteration of the innermost loop.
+// FUNC pops the name of the function defined, FORMAL declares the last
+// matched identifier its next parameter and ENDFUNC ends the definition.
+// DECL declares the variable on top of the stack a local of the function,
+// POP drops the top of the stack. ARGS begins the arguments of a call of
+// the function on top of the stack, ARG adds one and CALL emits the call and
+// pushes the temporary receiving its value. NONE pushes an omitted
+// expression and RETURN pops the value the function returns.
+var SEMANTIC_ACTIONS = map[string]SemanticAction{
+	"PUSH": func(translator *Translator, arg string) error {
+		operand, err := operandOf(translator.Last)
+		if err != nil {
+			return err
+		}
+		translator.pushOperand(operand)
+		return nil
+	},
+	"GEQ": func(translator *Translator, arg string) error {
+		right, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		left, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		temp := translator.builder.NewTemp()
+		translator.builder.Emit(arg, left, right, temp)
+		translator.pushOperand(temp)
+		return nil
+	},
+	"UNARY": func(translator *Translator, arg string) error {
+		operand, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		temp := translator.builder.NewTemp()
+		translator.builder.Emit(arg, operand, ir.Operand{}, temp)
+		translator.pushOperand(temp)
+		return nil
+	},
+	"COMPOUND": func(translator *Translator, arg string) error {
+		value, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		target, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		if !target.IsVar() {
+			return fmt.Errorf("can not assign to %s", target)
+		}
+		temp := translator.builder.NewTemp()
+		translator.builder.Emit(arg, target, value, temp)
+		translator.builder.Emit("=", temp, ir.Operand{}, target)
+		return nil
+	},
+	"ASSIGN": func(translator *Translator, arg string) error {
+		value, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		target, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		if !target.IsVar() {
+			return fmt.Errorf("can not assign to %s", target)
+		}
+		translator.builder.Emit("=", value, ir.Operand{}, target)
+		return nil
+	},
+	"VAL": func(translator *Translator, arg string) error {
+		operand, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		translator.pushOperand(operand)
+		return nil
+	},
+	"REL": func(translator *Translator, arg string) error {
+		right, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		left, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		builder := translator.builder
+		translator.push(Value{
+			boolean:   true,
+			truelist:  []int{builder.Emit("j"+arg, left, right, ir.Operand{})},
+			falselist: []int{builder.Emit("j", ir.Operand{}, ir.Operand{}, ir.Operand{})},
+		})
+		return nil
+	},
+	"OR": func(translator *Translator, arg string) error {
+		left, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(left.falselist, translator.builder.PlaceLabel())
+		translator.push(Value{boolean: true, truelist: left.truelist})
+		return nil
+	},
+	"ENDOR": func(translator *Translator, arg string) error {
+		right, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		left, err := translator.pop()
+		if err != nil {
+			return err
+		}
+		translator.push(Value{
+			boolean:   true,
+			truelist:  append(left.truelist, right.truelist...),
+			falselist: right.falselist,
+		})
+		return nil
+	},
+	"AND": func(translator *Translator, arg string) error {
+		left, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(left.truelist, translator.builder.PlaceLabel())
+		translator.push(Value{boolean: true, falselist: left.falselist})
+		return nil
+	},
+	"ENDAND": func(translator *Translator, arg string) error {
+		right, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		left, err := translator.pop()
+		if err != nil {
+			return err
+		}
+		translator.push(Value{
+			boolean:   true,
+			truelist:  right.truelist,
+			falselist: append(left.falselist, right.falselist...),
+		})
+		return nil
+	},
+	"NOT": func(translator *Translator, arg string) error {
+		value, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		value.truelist, value.falselist = value.falselist, value.truelist
+		translator.push(value)
+		return nil
+	},
+	"THEN": func(translator *Translator, arg string) error {
+		condition, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(condition.truelist, translator.builder.PlaceLabel())
+		translator.push(Value{boolean: true, falselist: condition.falselist})
+		return nil
+	},
+	"ELSE": func(translator *Translator, arg string) error {
+		then, err := translator.pop()
+		if err != nil {
+			return err
+		}
+		end := translator.jump(ir.Operand{})
+		translator.builder.Backpatch(then.falselist, translator.builder.PlaceLabel())
+		translator.push(Value{boolean: true, falselist: []int{end}})
+		return nil
+	},
+	"ENDIF": func(translator *Translator, arg string) error {
+		then, err := translator.pop()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(then.falselist, translator.builder.PlaceLabel())
+		return nil
+	},
+	"LOOP": func(translator *Translator, arg string) error {
+		translator.loops = append(translator.loops, &Loop{begin: translator.builder.PlaceLabel()})
+		return nil
+	},
+	"DO": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		condition, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(condition.truelist, translator.builder.PlaceLabel())
+		loop.breaks = append(loop.breaks, condition.falselist...)
+		return nil
+	},
+	"ENDWHILE": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		translator.jump(loop.begin)
+		translator.builder.Backpatch(loop.continues, loop.begin)
+		translator.endLoop(loop)
+		return nil
+	},
+	"DOCOND": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(loop.continues, translator.builder.PlaceLabel())
+		loop.continues = nil
+		return nil
+	},
+	"ENDDO": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		condition, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		translator.builder.Backpatch(condition.truelist, loop.begin)
+		loop.breaks = append(loop.breaks, condition.falselist...)
+		translator.endLoop(loop)
+		return nil
+	},
+	"NONE": func(translator *Translator, arg string) error {
+		translator.push(Value{})
+		return nil
+	},
+	"FORSTEP": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		if translator.popNone() {
+			translator.push(Value{boolean: true, truelist: []int{translator.jump(ir.Operand{})}})
+		}
+		condition, err := translator.popCondition()
+		if err != nil {
+			return err
+		}
+		loop.body = condition.truelist
+		loop.breaks = append(loop.breaks, condition.falselist...)
+		loop.step = translator.builder.PlaceLabel()
+		return nil
+	},
+	"FORBODY": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		translator.jump(loop.begin)
+		translator.builder.Backpatch(loop.body, translator.builder.PlaceLabel())
+		return nil
+	},
+	"ENDFOR": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return err
+		}
+		translator.jump(loop.step)
+		translator.builder.Backpatch(loop.continues, loop.step)
+		translator.endLoop(loop)
+		return nil
+	},
+	"BREAK": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return fmt.Errorf("break %s", err)
+		}
+		loop.breaks = append(loop.breaks, translator.jump(ir.Operand{}))
+		return nil
+	},
+	"CONTINUE": func(translator *Translator, arg string) error {
+		loop, err := translator.loop()
+		if err != nil {
+			return fmt.Errorf("continue %s", err)
+		}
+		loop.continues = append(loop.continues, translator.jump(ir.Operand{}))
+		return nil
+	},
+	"FUNC": func(translator *Translator, arg string) error {
+		name, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		if translator.function.IsFunc() {
+			return fmt.Errorf("%s is defined within %s", name, translator.function)
+		}
+		translator.function = ir.Func(name.Name)
+		translator.formals = 0
+		translator.builder.Emit(ir.FUNC_OP, ir.Operand{}, ir.Operand{}, translator.function)
+		return nil
+	},
+	"FORMAL": func(translator *Translator, arg string) error {
+		operand, err := operandOf(translator.Last)
+		if err != nil {
+			return err
+		}
+		translator.builder.Emit(ir.FORMAL_OP, ir.Const(translator.formals), ir.Operand{}, operand)
+		translator.formals++
+		return nil
+	},
+	"ENDFUNC": func(translator *Translator, arg string) error {
+		translator.builder.Emit(ir.ENDFUNC_OP, ir.Operand{}, ir.Operand{}, translator.function)
+		translator.function = ir.Operand{}
+		return nil
+	},
+	"DECL": func(translator *Translator, arg string) error {
+		if len(translator.SEM) == 0 {
+			return errors.New("semantic stack is empty")
+		}
+		if translator.function.IsFunc() {
+			translator.builder.Emit(ir.LOCAL_OP, ir.Operand{}, ir.Operand{}, translator.SEM[len(translator.SEM)-1].operand)
+		}
+		return nil
+	},
+	"POP": func(translator *Translator, arg string) error {
+		_, err := translator.pop()
+		return err
+	},
+	"ARGS": func(translator *Translator, arg string) error {
+		translator.push(Value{call: true, args: make([]ir.Operand, 0)})
+		return nil
+	},
+	"ARG": func(translator *Translator, arg string) error {
+		operand, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		call, err := translator.pop()
+		if err != nil {
+			return err
+		}
+		call.args = append(call.args, operand)
+		translator.push(call)
+		return nil
+	},
+	"CALL": func(translator *Translator, arg string) error {
+		call, err := translator.pop()
+		if err != nil {
+			return err
+		}
+		name, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		if !name.IsVar() {
+			return fmt.Errorf("can not call %s", name)
+		}
+		//the arguments are passed only now, so that the calls among them
+		//do not come between the params of this one
+		builder := translator.builder
+		for _, v := range call.args {
+			builder.Emit(ir.PARAM_OP, v, ir.Operand{}, ir.Operand{})
+		}
+		temp := builder.NewTemp()
+		builder.Emit(ir.CALL_OP, ir.Func(name.Name), ir.Const(len(call.args)), temp)
+		translator.pushOperand(temp)
+		return nil
+	},
+	"RETURN": func(translator *Translator, arg string) error {
+		if !translator.function.IsFunc() {
+			return errors.New("return outside of a function")
+		}
+		if translator.popNone() {
+			translator.builder.Emit(ir.RETURN_OP, ir.Operand{}, ir.Operand{}, ir.Operand{})
+			return nil
+		}
+		operand, err := translator.popOperand()
+		if err != nil {
+			return err
+		}
+		translator.builder.Emit(ir.RETURN_OP, operand, ir.Operand{}, ir.Operand{})
+		return nil
+	},
+}
+
+// RegisterAction adds an action usable as {name} or {name(arg)} in the
+// grammar file, it must be called before the grammar is built.
+func RegisterAction(name string, action SemanticAction) {
+	SEMANTIC_ACTIONS[name] = action
+}
+
+func IsAction(token uint8) bool {
+	return token >= ACTION_BASE
+}
+
+// CastSymbols replaces the names of charcast outside of action symbols by
+// their character, the longest name first.
+func CastSymbols(s string, charcast map[string]uint8) string {
+	out := ""
+	for i := 0; i < len(s); {
+		if s[i] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			out += s[i : i+end+1]
+			i += end + 1
+			continue
+		}
+		name := ""
+		for k := range charcast {
+			if strings.HasPrefix(s[i:], k) && len(k) > len(name) {
+				name = k
+			}
+		}
+		if name != "" {
+			out += string([]uint8{charcast[name]})
+			i += len(name)
+		} else {
+			out += string(s[i])
+			i++
+		}
+	}
+	return out
+}
+
+// SplitAlternatives splits the right side of a rule at the | outside of
+// action symbols.
+func SplitAlternatives(s string) []string {
+	alternatives := make([]string, 0)
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '|':
+			if depth == 0 {
+				alternatives = append(alternatives, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, s[start:])
+}
+
+// Scheme are the action symbols of a translation scheme, in the order they
+// were first read.
+type Scheme struct {
+	Actions []ActionSymbol
+}
+
+// ReadAlternative turns an alternative of the grammar file into the
+// production with its action symbols and the production without them, on
+// which FIRST and FOLLOW are computed. An alternative made only of actions
+// derives e.
+func (scheme *Scheme) ReadAlternative(alternative string) ([]uint8, []uint8, error) {
+	translation := make([]uint8, 0)
+	production := make([]uint8, 0)
+	for i := 0; i < len(alternative); i++ {
+		if alternative[i] != '{' {
+			translation = append(translation, alternative[i])
+			production = append(production, alternative[i])
+			continue
+		}
+		end := strings.IndexByte(alternative[i:], '}')
+		if end < 0 {
+			return nil, nil, errors.New("grammar file format error, unterminated action")
+		}
+		symbol, err := scheme.Symbol(alternative[i+1 : i+end])
+		if err != nil {
+			return nil, nil, err
+		}
+		translation = append(translation, symbol)
+		i += end
+	}
+	if len(production) == 0 {
+		production = []uint8("e")
+	}
+	if len(translation) == 0 {
+		translation = []uint8("e")
+	}
+	return translation, production, nil
+}
+
+// Symbol returns the byte of the action NAME or NAME(arg), the same action
+// written twice gets the same byte.
+func (scheme *Scheme) Symbol(text string) (uint8, error) {
+	action := ActionSymbol{Name: text}
+	if open := strings.IndexByte(text, '('); open >= 0 && strings.HasSuffix(text, ")") {
+		action = ActionSymbol{Name: text[:open], Arg: text[open+1 : len(text)-1]}
+	}
+	if _, ok := SEMANTIC_ACTIONS[action.Name]; !ok {
+		return 0, fmt.Errorf("unknown semantic action %s", action.Name)
+	}
+	for i, v := range scheme.Actions {
+		if v == action {
+			return uint8(ACTION_BASE + i), nil
+		}
+	}
+	if len(scheme.Actions) == 256-ACTION_BASE {
+		return 0, errors.New("too many action symbols")
+	}
+	scheme.Actions = append(scheme.Actions, action)
+	return uint8(ACTION_BASE + len(scheme.Actions) - 1), nil
+}
+
+// Action is the action of an action symbol.
+func (scheme *Scheme) Action(symbol uint8) ActionSymbol {
+	return scheme.Actions[symbol-ACTION_BASE]
+}
+
+// Run runs the action of an action symbol on the translator.
+func (scheme *Scheme) Run(symbol uint8, translator *Translator) error {
+	action := scheme.Action(symbol)
+	if err := SEMANTIC_ACTIONS[action.Name](translator, action.Arg); err != nil {
+		return fmt.Errorf("action %s: %s", action, err)
+	}
+	return nil
+}
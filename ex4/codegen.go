@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"ex3/ir"
+)
+
+// ARG_REGS are the registers of the first integer arguments in the System V
+// AMD64 calling convention, as 32 and as 64 bit registers. The other
+// arguments are pushed on the stack, the last one first.
+var ARG_REGS = [][2]string{
+	{"edi", "rdi"},
+	{"esi", "rsi"},
+	{"edx", "rdx"},
+	{"ecx", "rcx"},
+	{"r8d", "r8"},
+	{"r9d", "r9"},
+}
+
+// Frame is the stack frame of a function: the offset from rbp of each of
+// its parameters, local variables and temporaries, and its size, kept a
+// multiple of 16 so that calls find the stack aligned.
+type Frame struct {
+	slots map[ir.Operand]int
+	size  int
+}
+
+// Function is a function the program defines, the quads from its func to
+// its endfunc and its frame.
+type Function struct {
+	name  string
+	quads []ir.Quad
+	frame *Frame
+}
+
+// Program is the quads of a translation arranged for the code generator:
+// the functions defined, the quads outside of them, the global storage and
+// the string literals, each with its index in .LS0, .LS1, ...
+type Program struct {
+	functions []*Function
+	toplevel  []ir.Quad
+	globals   []ir.Operand
+	literals  []string
+	strings   map[string]int
+	main      bool
+}
+
+func newFrame(quads []ir.Quad) *Frame {
+	frame := &Frame{slots: make(map[ir.Operand]int)}
+	for _, v := range ir.Storage(quads) {
+		//a variable that is neither a parameter nor declared is global
+		local := v.IsTemp()
+		for _, q := range quads {
+			if (q.Op == ir.FORMAL_OP || q.Op == ir.LOCAL_OP) && q.Result == v {
+				local = true
+			}
+		}
+		if local {
+			frame.size += 4
+			frame.slots[v] = -frame.size
+		}
+	}
+	frame.size = (frame.size + 15) / 16 * 16
+	return frame
+}
+
+// holds tells whether an operand lives in the frame, a nil frame holds
+// nothing.
+func (frame *Frame) holds(operand ir.Operand) bool {
+	if frame == nil {
+		return false
+	}
+	_, ok := frame.slots[operand]
+	return ok
+}
+
+// newProgram splits the quads into functions and sets aside the storage
+// and literals they need.
+func newProgram(quads []ir.Quad) (*Program, error) {
+	program := &Program{strings: make(map[string]int)}
+	var function *Function
+	for _, v := range quads {
+		switch {
+		case v.Op == ir.FUNC_OP:
+			function = &Function{name: v.Result.Name}
+			program.main = program.main || function.name == "main"
+		case v.Op == ir.ENDFUNC_OP:
+			function.frame = newFrame(function.quads)
+			program.functions = append(program.functions, function)
+			function = nil
+		case function != nil:
+			function.quads = append(function.quads, v)
+		default:
+			program.toplevel = append(program.toplevel, v)
+		}
+		for _, operand := range []ir.Operand{v.Arg1, v.Arg2, v.Result} {
+			if !operand.IsString() {
+				continue
+			}
+			if v.Op != ir.PARAM_OP {
+				return nil, fmt.Errorf("string literal %s can only be passed to a function", operand)
+			}
+			if _, ok := program.strings[operand.Name]; !ok {
+				program.strings[operand.Name] = len(program.literals)
+				program.literals = append(program.literals, operand.Name)
+			}
+		}
+	}
+	seen := make(map[ir.Operand]bool)
+	addGlobals := func(quads []ir.Quad, frame *Frame) {
+		for _, v := range ir.Storage(quads) {
+			if !frame.holds(v) && !seen[v] {
+				seen[v] = true
+				program.globals = append(program.globals, v)
+			}
+		}
+	}
+	addGlobals(program.toplevel, nil)
+	for _, function := range program.functions {
+		addGlobals(function.quads, function.frame)
+	}
+	return program, nil
+}
+
+// assignedVariables are the variables the code outside of functions
+// assigns, in order of their first assignment, by = or once optimized by
+// any operator. Without a main function that code is the program and each
+// is printed when it ends.
+func (program *Program) assignedVariables() []ir.Operand {
+	seen := make(map[ir.Operand]bool)
+	variables := make([]ir.Operand, 0)
+	for _, v := range program.toplevel {
+		if v.Result.IsVar() && !seen[v.Result] {
+			seen[v.Result] = true
+			variables = append(variables, v.Result)
+		}
+	}
+	return variables
+}
+
+func build_data(program *Program) string {
+	output_data := ".intel_syntax noprefix\n.data\n"
+	for _, v := range program.globals {
+		output_data += fmt.Sprintf("%s:\n", symbolOf(v))
+		output_data += "        .long   0\n"
+	}
+	if !program.main {
+		for i, v := range program.assignedVariables() {
+			output_data += fmt.Sprintf(".LC%d:\n", i)
+			output_data += fmt.Sprintf("        .string \"%s = %%d\\n\"\n", v.Name)
+		}
+	}
+	for i, v := range program.literals {
+		output_data += fmt.Sprintf(".LS%d:\n", i)
+		output_data += fmt.Sprintf("        .string %s\n", v)
+	}
+	return output_data
+}
+
+// symbolOf is the data label of a variable or a temporary, variables get a
+// prefix so they never clash with the temporaries or the functions
+func symbolOf(operand ir.Operand) string {
+	if operand.IsVar() {
+		return "v_" + operand.Name
+	}
+	return operand.String()
+}
+
+// asmOperand is a constant as an immediate, else the memory it lives in, the
+// frame of the function or the data section
+func asmOperand(operand ir.Operand, frame *Frame) string {
+	if operand.IsConst() {
+		return operand.String()
+	}
+	if frame.holds(operand) {
+		return fmt.Sprintf("DWORD PTR [rbp%d]", frame.slots[operand])
+	}
+	return fmt.Sprintf("DWORD PTR %s[rip]", symbolOf(operand))
+}
+
+// JCC is the x86 conditional jump of each relational jump quad
+var JCC = map[string]string{
+	"j<":  "jl",
+	"j>":  "jg",
+	"j<=": "jle",
+	"j>=": "jge",
+	"j==": "je",
+	"j!=": "jne",
+}
+
+func asmLabel(label ir.Operand) string {
+	return fmt.Sprintf(".L%d", label.Value)
+}
+
+// build_load loads an argument into a register, a string literal as its
+// address
+func (program *Program) build_load(reg [2]string, operand ir.Operand, frame *Frame) string {
+	if operand.IsString() {
+		return fmt.Sprintf("	lea    %s, .LS%d[rip]\n", reg[1], program.strings[operand.Name])
+	}
+	return fmt.Sprintf("	mov    %s, %s\n", reg[0], asmOperand(operand, frame))
+}
+
+// build_call passes the arguments, calls the function and stores its value.
+// eax is cleared because a variadic function like printf reads the number
+// of vector registers used from it.
+func (program *Program) build_call(v ir.Quad, params []ir.Operand, frame *Frame) string {
+	out_string := ""
+	stacked := len(params) - len(ARG_REGS)
+	if stacked < 0 {
+		stacked = 0
+	}
+	//keep rsp a multiple of 16 at the call
+	if stacked%2 == 1 {
+		out_string += "	sub    rsp, 8\n"
+	}
+	for i := len(params) - 1; i >= len(ARG_REGS); i-- {
+		out_string += program.build_load([2]string{"eax", "rax"}, params[i], frame)
+		out_string += "	push   rax\n"
+	}
+	for i := 0; i < len(params) && i < len(ARG_REGS); i++ {
+		out_string += program.build_load(ARG_REGS[i], params[i], frame)
+	}
+	out_string += "	mov    eax, 0\n"
+	out_string += fmt.Sprintf("	call   %s\n", v.Arg1.Name)
+	if stacked > 0 {
+		out_string += fmt.Sprintf("	add    rsp, %d\n", 8*(stacked+stacked%2))
+	}
+	out_string += fmt.Sprintf("	mov    %s, eax\n", asmOperand(v.Result, frame))
+	return out_string
+}
+
+func (program *Program) build_inner_text(quads []ir.Quad, frame *Frame) string {
+	out_string := ""
+	params := make([]ir.Operand, 0)
+	for _, v := range quads {
+		if v.IsLabel() {
+			out_string += fmt.Sprintf("%s:\n", asmLabel(v.Result))
+			continue
+		}
+		if v.Op == "j" {
+			out_string += fmt.Sprintf("	jmp    %s\n", asmLabel(v.Result))
+			continue
+		}
+		switch v.Op {
+		case ir.LOCAL_OP:
+			continue
+		case ir.FORMAL_OP:
+			//the parameters are copied from their registers or from above
+			//the return address into the frame
+			if v.Arg1.Value < len(ARG_REGS) {
+				out_string += fmt.Sprintf("	mov    %s, %s\n", asmOperand(v.Result, frame), ARG_REGS[v.Arg1.Value][0])
+			} else {
+				out_string += fmt.Sprintf("	mov    eax, DWORD PTR [rbp+%d]\n", 16+8*(v.Arg1.Value-len(ARG_REGS)))
+				out_string += fmt.Sprintf("	mov    %s, eax\n", asmOperand(v.Result, frame))
+			}
+			continue
+		case ir.PARAM_OP:
+			params = append(params, v.Arg1)
+			continue
+		case ir.CALL_OP:
+			out_string += program.build_call(v, params, frame)
+			params = params[:0]
+			continue
+		case ir.RETURN_OP:
+			if v.Arg1.Kind != ir.NONE {
+				out_string += fmt.Sprintf("	mov    eax, %s\n", asmOperand(v.Arg1, frame))
+			}
+			out_string += "	leave\n"
+			out_string += "	ret\n"
+			continue
+		}
+		out_string += fmt.Sprintf("	mov    eax, %s\n", asmOperand(v.Arg1, frame))
+		if v.Op == "jnz" {
+			out_string += "	cmp    eax, 0\n"
+			out_string += fmt.Sprintf("	jne    %s\n", asmLabel(v.Result))
+			continue
+		}
+		if jcc, ok := JCC[v.Op]; ok {
+			out_string += fmt.Sprintf("	cmp    eax, %s\n", asmOperand(v.Arg2, frame))
+			out_string += fmt.Sprintf("	%-6s %s\n", jcc, asmLabel(v.Result))
+			continue
+		}
+		if v.Op == "=" {
+			out_string += fmt.Sprintf("	mov    %s, eax\n", asmOperand(v.Result, frame))
+			continue
+		}
+		if v.Op == "neg" {
+			out_string += "	neg    eax\n"
+		} else if v.Op == "~" {
+			out_string += "	not    eax\n"
+		} else {
+			//the shift count must be in cl
+			out_string += fmt.Sprintf("	mov    ecx, %s\n", asmOperand(v.Arg2, frame))
+		}
+		if v.Op == "+" {
+			out_string += "	add    eax, ecx\n"
+		} else if v.Op == "-" {
+			out_string += "	sub    eax, ecx\n"
+		} else if v.Op == "*" {
+			out_string += "	imul   eax, ecx\n"
+		} else if v.Op == "/" || v.Op == "%" {
+			out_string += "	cdq\n"
+			out_string += "	idiv   ecx\n"
+			if v.Op == "%" {
+				out_string += "	mov    eax, edx\n"
+			}
+		} else if v.Op == "<<" {
+			out_string += "	sal    eax, cl\n"
+		} else if v.Op == ">>" {
+			out_string += "	sar    eax, cl\n"
+		} else if v.Op == "&" {
+			out_string += "	and    eax, ecx\n"
+		} else if v.Op == "|" {
+			out_string += "	or     eax, ecx\n"
+		} else if v.Op == "^" {
+			out_string += "	xor    eax, ecx\n"
+		}
+		out_string += fmt.Sprintf("	mov    %s, eax\n", asmOperand(v.Result, frame))
+	}
+
+	return out_string
+}
+
+// build_print prints every assigned variable as name = value
+func (program *Program) build_print() string {
+	out_string := ""
+	for i, v := range program.assignedVariables() {
+		out_string += fmt.Sprintf("	mov    esi, %s\n", asmOperand(v, nil))
+		out_string += fmt.Sprintf("	lea    rdi, .LC%d[rip]\n", i)
+		out_string += "	mov    eax, 0\n"
+		out_string += "	call   printf\n"
+	}
+	return out_string
+}
+
+func build_prologue(name string, size int) string {
+	return fmt.Sprintf(`
+.globl %s
+%s:
+	push    rbp
+	mov     rbp, rsp
+	sub     rsp, %d
+`, name, name, size)
+}
+
+// build_text writes the functions, a function falling off its end returns
+// 0. The code outside of functions runs first in main, without a main
+// function it is the body of one that prints the variables it assigns.
+func build_text(program *Program) string {
+	output_text := ".text\n"
+	for _, function := range program.functions {
+		output_text += build_prologue(function.name, function.frame.size)
+		quads := function.quads
+		if function.name == "main" {
+			//after the parameters are saved, the calls may clobber them
+			formals := 0
+			for formals < len(quads) && quads[formals].Op == ir.FORMAL_OP {
+				formals++
+			}
+			output_text += program.build_inner_text(quads[:formals], function.frame)
+			output_text += program.build_inner_text(program.toplevel, nil)
+			quads = quads[formals:]
+		}
+		output_text += program.build_inner_text(quads, function.frame)
+		output_text +=
+			`	mov     eax, 0
+	leave
+	ret
+`
+	}
+	if !program.main {
+		output_text += build_prologue("main", 16)
+		output_text += program.build_inner_text(program.toplevel, nil)
+		output_text += "\n"
+		output_text += program.build_print()
+		output_text +=
+			`	mov     eax, 0
+	leave
+	ret
+`
+	}
+	output_text += "	.section .note.GNU-stack,\"\",@progbits\n"
+	return output_text
+}
+func buildAssembleCode(quads []ir.Quad) error {
+	program, err := newProgram(quads)
+	if err != nil {
+		return err
+	}
+	output_str := ""
+	output_str += build_data(program)
+	output_str += build_text(program)
+	fmt.Print(output_str)
+	return ioutil.WriteFile("AssembleCode.S", []byte(output_str), 0666)
+}
@@ -1,3 +1,12 @@
 module ex4
 
 go 1.16
+
+require (
+	ex3 v0.0.0
+	example.com/m v0.0.0
+)
+
+replace ex3 => ../ex3
+
+replace example.com/m => ../ex1
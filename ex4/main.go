@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+
+	"ex3/ll1"
+)
+
+var sampleProgram = "a = 4/2+5*3; b = a*2; a = b-a;"
+
+func main() {
+	grammar_filename := flag.String("grammar", "../ex3/grammar.txt", "grammar file")
+	source_filename := flag.String("file", "", "compile this source file instead of the sample")
+	optimize := flag.Bool("O", true, "optimize the quads")
+	show_passes := flag.Bool("show-passes", false, "print the quads before and after each optimization pass")
+	cfg_filename := flag.String("cfg", "", "write the control flow graphs in DOT to this file, - for the standard output")
+	dataflow := flag.Bool("dataflow", false, "print reaching definitions, live variables and available expressions at each block")
+	flag.Parse()
+	Grammar := ll1.GrammarLL1{Optimize: *optimize, ShowPasses: *show_passes, CfgFilename: *cfg_filename, Dataflow: *dataflow, Backend: buildAssembleCode}
+	//read grammar
+	Grammar.BuildGrammar(*grammar_filename)
+	program := sampleProgram
+	if *source_filename != "" {
+		b, err := ioutil.ReadFile(*source_filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		program = string(b)
+	}
+	Grammar.ParseAndPrint(program)
+}